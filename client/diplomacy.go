@@ -0,0 +1,81 @@
+package main
+
+import (
+	"bytes"
+	"fmt"
+	"image"
+	"sort"
+	"strings"
+
+	"github.com/bmcszk/gptrts/pkg/game"
+	"github.com/hajimehoshi/ebiten/v2"
+	"github.com/hajimehoshi/ebiten/v2/ebitenutil"
+	"github.com/hajimehoshi/ebiten/v2/inpututil"
+)
+
+// nextStance - order in which D key cycles stance towards other player
+var nextStance = map[game.Stance]game.Stance{
+	game.AllyStance:    game.NeutralStance,
+	game.NeutralStance: game.EnemyStance,
+	game.EnemyStance:   game.AllyStance,
+}
+
+// updateDiplomacy handles D key, it changes stance towards owner of unit or building under the cursor
+func (g *clientGame) updateDiplomacy() {
+	if !ebiten.IsFocused() || !inpututil.IsKeyJustPressed(ebiten.KeyD) {
+		return
+	}
+	mx, my := ebiten.CursorPosition()
+	tileX, tileY := g.screenToWorldTiles(mx, my)
+	owner, ok := g.ownerAt(image.Pt(tileX, tileY))
+	if !ok || owner == g.playerId {
+		return
+	}
+	g.enDispatch(game.SetDiplomacyAction{
+		Type: game.SetDiplomacyActionType,
+		Payload: game.SetDiplomacyPayload{
+			PlayerId: g.playerId,
+			TargetId: owner,
+			Stance:   nextStance[game.StanceOf(g.store, g.playerId, owner)],
+		},
+	})
+}
+
+// ownerAt returns owner of visible unit or building at tile
+func (g *clientGame) ownerAt(p image.Point) (game.PlayerIdType, bool) {
+	t, ok := g.store.GetTile(p)
+	if !ok || t.Visibility != game.Visible {
+		return game.ZeroPlayerId, false
+	}
+	if t.Unit != nil {
+		return t.Unit.Owner, true
+	}
+	if t.Building != nil {
+		return t.Building.Owner, true
+	}
+	return game.ZeroPlayerId, false
+}
+
+// drawDiplomacy prints stance towards other players and their stance towards the player
+func (g *clientGame) drawDiplomacy(enScreen *ebiten.Image) {
+	if _, ok := g.store.GetPlayer(g.playerId); !ok {
+		return
+	}
+	players := g.store.GetAllPlayers()
+	sort.Slice(players, func(i, j int) bool {
+		return bytes.Compare(players[i].Id[:], players[j].Id[:]) < 0
+	})
+	lines := make([]string, 0, len(players))
+	for _, p := range players {
+		if p.Id == g.playerId {
+			continue
+		}
+		lines = append(lines, fmt.Sprintf("%s: %s / %s", p.Name,
+			game.StanceOf(g.store, g.playerId, p.Id), game.StanceOf(g.store, p.Id, g.playerId)))
+	}
+	if len(lines) == 0 {
+		return
+	}
+	w, _ := enScreen.Size()
+	ebitenutil.DebugPrintAt(enScreen, strings.Join(lines, "\n"), w-200, 0)
+}
@@ -1,18 +1,24 @@
 package main
 
 import (
+	"fmt"
 	"image"
 	"image/color"
 	"log"
+	"sort"
+	"strings"
 
 	"github.com/bmcszk/gptrts/pkg/convert"
 	"github.com/bmcszk/gptrts/pkg/game"
+	"github.com/google/uuid"
 	"github.com/hajimehoshi/ebiten/v2"
 	"github.com/hajimehoshi/ebiten/v2/ebitenutil"
+	"github.com/hajimehoshi/ebiten/v2/inpututil"
 )
 
 const (
 	cameraSpeed = 2
+	updatesSize = 1024 // changes from connection waiting for the next frame
 )
 
 type clientGame struct {
@@ -24,6 +30,21 @@ type clientGame struct {
 	selectionBox     *image.Rectangle
 	enDispatch       game.DispatchFunc
 	screen           *screen
+	mode             game.NetworkMode
+	defs             *game.Definitions
+	placing          *game.BuildingType // building chosen for placement, nil when not in build mode
+	building         *game.Building     // selected own building
+	lobby            game.Lobby
+	fog              *game.Fog
+	spectator        bool                                    // watches without units, also in replay viewer
+	watching         game.PlayerIdType                       // player whose vision spectator sees, whole map is visible when zero
+	seq              int                                     // sequence number of the last move command
+	inputs           []moveInput                             // move commands not acknowledged by server, see reconcile
+	predicted        map[game.UnitIdType]bool                // own units moved locally ahead of server
+	confirmed        map[game.UnitIdType]game.MoveStepAction // last state of predicted units from server
+	localTick        int
+	interp           *interpolator
+	updates          chan func() // changes from connection goroutine, applied at the start of Update, see apply
 }
 
 func newClientGame(playerId game.PlayerIdType, store game.Store, enDispatch game.DispatchFunc) *clientGame {
@@ -34,20 +55,99 @@ func newClientGame(playerId game.PlayerIdType, store game.Store, enDispatch game
 		GameLogic:  g,
 		enDispatch: enDispatch,
 		screen:     &emptyScreen,
+		fog:        game.NewFog(),
+		predicted:  make(map[game.UnitIdType]bool),
+		confirmed:  make(map[game.UnitIdType]game.MoveStepAction),
+		interp:     newInterpolator(interpolationDelay),
+		updates:    make(chan func(), updatesSize),
 	}
 
 	return cg
 }
 
+// resync replaces state with snapshot from server, terrain loaded before stays as it was last seen
+func (g *clientGame) resync(p game.ResyncPayload) {
+	s := p.Snapshot
+	old := g.Snapshot()
+	s.Tiles = append(old.Tiles, s.Tiles...)
+	s.ResourceNodes = append(old.ResourceNodes, s.ResourceNodes...)
+	store := game.NewStoreImpl()
+	logic := game.NewGameLogic(store)
+	logic.Restore(s)
+	g.store, g.GameLogic = store, logic
+	g.mode = p.Mode
+	g.lobby = p.Lobby
+	g.defs = p.Definitions
+	if g.defs != nil {
+		if err := g.defs.Init(); err != nil {
+			log.Println(err)
+		}
+	}
+	g.building = nil
+	g.placing = nil
+	g.resetPrediction()
+	g.updateVisibility()
+}
+
 func (g *clientGame) HandleAction(action game.Action, dispatch game.DispatchFunc) {
 	log.Printf("client handle %s", action.GetType())
-	g.GameLogic.HandleAction(action, dispatch)
-	switch action.(type) {
-	case game.SpawnUnitAction, game.MoveStepAction, game.PlayerJoinSuccessAction, game.MapLoadSuccessAction:
+	if a, ok := action.(game.MoveStepAction); ok {
+		g.reconcile(a, dispatch)
+	} else {
+		g.GameLogic.HandleAction(action, dispatch)
+	}
+	g.updateInterpolation(action)
+	switch a := action.(type) {
+	case game.PlayerJoinSuccessAction:
+		g.spectator = a.Payload.Spectator
+		g.mode = a.Payload.Mode
+		g.lobby = a.Payload.Lobby
+		g.defs = a.Payload.Definitions
+		if g.defs != nil {
+			if err := g.defs.Init(); err != nil {
+				log.Println(err)
+			}
+		}
 		g.updateVisibility()
+	case game.ResyncAction:
+		g.resync(a.Payload)
+	case game.SpawnUnitAction, game.MoveStepAction, game.MapLoadSuccessAction, game.UnitDiedAction,
+		game.BuildingPlacedAction, game.BuildingDestroyedAction, game.UnitEnteredVisionAction, game.UnitLeftVisionAction:
+		g.updateVisibility()
+	case game.TickAction:
+		g.updateVisibility()
+		g.sendChecksum(a.Payload.Tick)
+	case game.LobbyStateAction:
+		g.lobby = a.Payload
+		g.updateVisibility()
+	case game.DiplomacyChangedAction:
+		g.updateVisibility()
+	case game.PlayerDefeatedAction:
+		log.Printf("player %s defeated by %s", uuid.UUID(a.Payload.PlayerId), a.Payload.Reason)
+	case game.MatchSavedAction:
+		log.Printf("match saved at tick %d to %s", a.Payload.Tick, a.Payload.File)
+	case game.MatchSaveFailedAction:
+		log.Printf("match save failed: %s", a.Payload.Reason)
+	case game.DesyncAction:
+		log.Printf("desync of player %s at tick %d", uuid.UUID(a.Payload.PlayerId), a.Payload.Tick)
 	}
 }
 
+func (g *clientGame) sendChecksum(tick int) {
+	// server compares checksums of players only
+	if g.spectator || tick%game.ChecksumInterval != 0 {
+		return
+	}
+	g.enDispatch(game.StateChecksumAction{
+		Type: game.StateChecksumActionType,
+		Payload: game.StateChecksumPayload{
+			PlayerId: g.playerId,
+			Tick:     tick,
+			Checksum: g.Checksum(),
+		},
+	})
+}
+
 func (g *clientGame) Layout(outsideWidth, outsideHeight int) (int, int) {
 	/* // Calculate the desired screen size based on the size of the map
 	sw := len(g.Map.Tiles[0]) * tileSize
@@ -81,7 +181,7 @@ func (g *clientGame) Layout(outsideWidth, outsideHeight int) (int, int) {
 
 func (g *clientGame) Draw(enScreen *ebiten.Image) {
 	// Draw the map
-	g.screen.draw(enScreen, g.centerX+g.cameraX, g.centerY+g.cameraY)
+	g.screen.draw(enScreen, g.centerX+g.cameraX, g.centerY+g.cameraY, g.fog.Ghosts(), g.unitPosition)
 
 	// Draw the selection box
 	if g.selectionBox != nil {
@@ -92,25 +192,172 @@ func (g *clientGame) Draw(enScreen *ebiten.Image) {
 		col := color.RGBA{0, 255, 0, 128}
 		ebitenutil.DrawRect(enScreen, float64(x1), float64(y1), float64(x2-x1), float64(y2-y1), col)
 	}
+
+	g.drawPlacement(enScreen)
+	g.drawProduction(enScreen)
+	g.drawResources(enScreen)
+	g.drawDiplomacy(enScreen)
+	g.drawEndScreen(enScreen)
+	g.drawLobby(enScreen)
+	g.drawSpectator(enScreen)
+}
+
+// drawEndScreen covers the map with result of the match, or with defeat while others still play
+func (g *clientGame) drawEndScreen(enScreen *ebiten.Image) {
+	result := g.Result()
+	player, ok := g.store.GetPlayer(g.playerId)
+	defeated := ok && player.Defeated
+	if result == nil && !defeated {
+		return
+	}
+	w, h := enScreen.Size()
+	ebitenutil.DrawRect(enScreen, 0, 0, float64(w), float64(h), color.RGBA{0, 0, 0, 192})
+
+	lines := []string{"DEFEAT"}
+	if result != nil {
+		lines = []string{g.resultTitle(*result), fmt.Sprintf("by %s after %ds", result.Reason, result.Tick/game.TickRate), ""}
+		for _, s := range result.Scores {
+			name := uuid.UUID(s.PlayerId).String()
+			if p, ok := g.store.GetPlayer(s.PlayerId); ok && p.Name != "" {
+				name = p.Name
+			}
+			lines = append(lines, fmt.Sprintf("%s: %d", name, s.Score))
+		}
+	}
+	ebitenutil.DebugPrintAt(enScreen, strings.Join(lines, "\n"), w/2-64, h/2-16*len(lines)/2)
+}
+
+func (g *clientGame) resultTitle(result game.GameOverPayload) string {
+	if len(result.Winners) == 0 {
+		return "DRAW"
+	}
+	if _, ok := g.store.GetPlayer(g.playerId); !ok {
+		return "GAME OVER"
+	}
+	for _, id := range result.Winners {
+		if id == g.playerId {
+			return "VICTORY"
+		}
+	}
+	return "DEFEAT"
+}
+
+// drawProduction prints queue of selected building and marks its rally point
+func (g *clientGame) drawProduction(enScreen *ebiten.Image) {
+	b := g.building
+	if b == nil || !b.IsAlive() {
+		return
+	}
+	x, y := g.worldToScreen(b.Rally.X*tileSize, b.Rally.Y*tileSize)
+	ebitenutil.DrawRect(enScreen, float64(x+tileSize/4), float64(y+tileSize/4), tileSize/2, tileSize/2, color.RGBA{255, 255, 255, 255})
+
+	lines := []string{string(b.Type)}
+	for i, t := range b.Produces {
+		lines = append(lines, fmt.Sprintf("%d: %s", i+1, t))
+	}
+	for i, item := range b.Queue {
+		line := string(item.Unit.Type)
+		if i == 0 {
+			line = fmt.Sprintf("%s %d%%", line, item.Progress*100/item.Time)
+		}
+		lines = append(lines, line)
+	}
+	_, h := enScreen.Size()
+	ebitenutil.DebugPrintAt(enScreen, strings.Join(lines, "\n"), 0, h-16*len(lines))
+}
+
+// drawPlacement draws footprint of building chosen for placement under the cursor
+func (g *clientGame) drawPlacement(enScreen *ebiten.Image) {
+	if g.placing == nil {
+		return
+	}
+	mx, my := ebiten.CursorPosition()
+	tileX, tileY := g.screenToWorldTiles(mx, my)
+	x, y := g.worldToScreen(tileX*tileSize, tileY*tileSize)
+	col := color.RGBA{0, 128, 0, 128}
+	if !g.canPlace(*g.placing, image.Pt(tileX, tileY)) {
+		col = color.RGBA{128, 0, 0, 128}
+	}
+	ebitenutil.DrawRect(enScreen, float64(x), float64(y), float64(g.placing.Width*tileSize), float64(g.placing.Height*tileSize), col)
+	ebitenutil.DebugPrintAt(enScreen, g.placing.Name, x, y-16)
+}
+
+// canPlace tells whether footprint is free of obstacles as far as the client knows, server decides finally
+func (g *clientGame) canPlace(t game.BuildingType, p image.Point) bool {
+	for _, fp := range game.FootprintPoints(p, image.Pt(t.Width, t.Height)) {
+		tile, ok := g.store.GetTile(fp)
+		if !ok {
+			return false
+		}
+		if tile.Unit != nil || tile.Building != nil || (tile.Resource != nil && tile.Resource.Amount > 0) || !game.TerrainOf(tile).Passable {
+			return false
+		}
+	}
+	return g.store.GetResources(g.playerId).Covers(t.Cost)
+}
+
+// drawResources prints stockpile of the player
+func (g *clientGame) drawResources(enScreen *ebiten.Image) {
+	resources := g.store.GetResources(g.playerId)
+	types := make([]string, 0, len(resources))
+	for t := range resources {
+		types = append(types, string(t))
+	}
+	sort.Strings(types)
+	parts := make([]string, 0, len(types))
+	for _, t := range types {
+		parts = append(parts, fmt.Sprintf("%s: %d", t, resources[game.ResourceType(t)]))
+	}
+	ebitenutil.DebugPrint(enScreen, strings.Join(parts, "  "))
+}
+
+// apply queues change of game state made outside of ebiten loop, e.g. action from server
+func (g *clientGame) apply(update func()) {
+	g.updates <- update
+}
+
+// applyUpdates runs queued changes, Update and Draw read state on ebiten goroutine only
+func (g *clientGame) applyUpdates() {
+	for {
+		select {
+		case update := <-g.updates:
+			update()
+		default:
+			return
+		}
+	}
 }
 
 func (g *clientGame) Update() error {
-	// Move camera with arrow keys
-	if ebiten.IsKeyPressed(ebiten.KeyArrowLeft) {
-		g.cameraX -= cameraSpeed
+	g.applyUpdates()
+	if g.spectator {
+		g.updateCamera()
+		g.updateSpectator()
+		return nil
 	}
-	if ebiten.IsKeyPressed(ebiten.KeyArrowRight) {
-		g.cameraX += cameraSpeed
+	if !g.lobby.IsPlaying() {
+		g.updateLobby()
+		return nil
 	}
-	if ebiten.IsKeyPressed(ebiten.KeyArrowUp) {
-		g.cameraY -= cameraSpeed
+
+	if g.mode == game.AuthoritativeMode {
+		g.predict()
 	}
-	if ebiten.IsKeyPressed(ebiten.KeyArrowDown) {
-		g.cameraY += cameraSpeed
+	g.updateCamera()
+	g.updatePlacement()
+	g.updateProduction()
+	g.updateDiplomacy()
+
+	// F5 saves the match, server accepts it only from admins
+	if ebiten.IsFocused() && inpututil.IsKeyJustPressed(ebiten.KeyF5) {
+		g.enDispatch(game.SaveMatchAction{
+			Type:    game.SaveMatchActionType,
+			Payload: game.SaveMatchPayload{PlayerId: g.playerId},
+		})
 	}
 
 	// Handle left mouse button click to select units
-	if ebiten.IsMouseButtonPressed(ebiten.MouseButtonLeft) && ebiten.IsFocused() {
+	if g.placing == nil && ebiten.IsMouseButtonPressed(ebiten.MouseButtonLeft) && ebiten.IsFocused() {
 		mx, my := ebiten.CursorPosition()
 		worldX, worldY := g.screenToWorld(mx, my)
 
@@ -135,49 +382,218 @@ func (g *clientGame) Update() error {
 	}
 
 	// Handle right mouse button click to move selected units
-	if ebiten.IsMouseButtonPressed(ebiten.MouseButtonRight) && ebiten.IsFocused() {
+	if g.placing == nil && ebiten.IsMouseButtonPressed(ebiten.MouseButtonRight) && ebiten.IsFocused() {
 		mx, my := ebiten.CursorPosition()
 		tileX, tileY := g.screenToWorldTiles(mx, my)
+		enemy := g.enemyAt(image.Pt(tileX, tileY))
+		enemyBuilding := g.enemyBuildingAt(image.Pt(tileX, tileY))
+		resource := g.resourceAt(image.Pt(tileX, tileY))
 		for _, u := range g.store.GetUnitsByPlayerId(g.playerId) {
 			if !u.Selected {
 				continue
 			}
-			moveStartAction := game.MoveStartAction{
-				Type: game.MoveStartActionType,
-				Payload: game.MoveStartPayload{
-					UnitId: u.Id,
-					Point:  image.Pt(tileX, tileY),
-				},
+			if resource != nil && u.HasAbility(game.GatherAbility) {
+				if u.Gather == nil || *u.Gather != resource.Point {
+					g.enDispatch(game.GatherStartAction{
+						Type: game.GatherStartActionType,
+						Payload: game.GatherStartPayload{
+							UnitId: u.Id,
+							Point:  resource.Point,
+						},
+					})
+				}
+				continue
+			}
+			if enemy != nil {
+				if u.Target != enemy.Id {
+					g.enDispatch(game.AttackAction{
+						Type: game.AttackActionType,
+						Payload: game.AttackPayload{
+							UnitId:   u.Id,
+							TargetId: enemy.Id,
+						},
+					})
+				}
+				continue
+			}
+			if enemyBuilding != nil {
+				if u.TargetBuilding != enemyBuilding.Id {
+					g.enDispatch(game.AttackAction{
+						Type: game.AttackActionType,
+						Payload: game.AttackPayload{
+							UnitId:     u.Id,
+							BuildingId: enemyBuilding.Id,
+						},
+					})
+				}
+				continue
 			}
-			g.enDispatch(moveStartAction)
+			g.moveUnit(u, image.Pt(tileX, tileY))
 		}
 	}
 
-	for _, u := range g.store.GetAllUnits() {
-		u.Update(g.enDispatch)
+	return nil
+}
+
+// updateCamera moves camera with arrow keys
+func (g *clientGame) updateCamera() {
+	if ebiten.IsKeyPressed(ebiten.KeyArrowLeft) {
+		g.cameraX -= cameraSpeed
+	}
+	if ebiten.IsKeyPressed(ebiten.KeyArrowRight) {
+		g.cameraX += cameraSpeed
+	}
+	if ebiten.IsKeyPressed(ebiten.KeyArrowUp) {
+		g.cameraY -= cameraSpeed
+	}
+	if ebiten.IsKeyPressed(ebiten.KeyArrowDown) {
+		g.cameraY += cameraSpeed
 	}
+}
 
-	return nil
+// updatePlacement handles build mode, B cycles through building types, left click places, right click or Esc cancels
+func (g *clientGame) updatePlacement() {
+	if g.defs == nil || len(g.defs.Buildings) == 0 {
+		return
+	}
+	if inpututil.IsKeyJustPressed(ebiten.KeyB) {
+		next := 0
+		if g.placing != nil {
+			for i, t := range g.defs.Buildings {
+				if t.Id == g.placing.Id {
+					next = i + 1
+				}
+			}
+		}
+		g.placing = nil
+		if next < len(g.defs.Buildings) {
+			g.placing = &g.defs.Buildings[next]
+		}
+	}
+	if g.placing == nil {
+		return
+	}
+	if inpututil.IsKeyJustPressed(ebiten.KeyEscape) || inpututil.IsMouseButtonJustPressed(ebiten.MouseButtonRight) {
+		g.placing = nil
+		return
+	}
+	if inpututil.IsMouseButtonJustPressed(ebiten.MouseButtonLeft) && ebiten.IsFocused() {
+		mx, my := ebiten.CursorPosition()
+		tileX, tileY := g.screenToWorldTiles(mx, my)
+		g.enDispatch(game.PlaceBuildingAction{
+			Type: game.PlaceBuildingActionType,
+			Payload: game.PlaceBuildingPayload{
+				PlayerId: g.playerId,
+				Type:     g.placing.Id,
+				Point:    image.Pt(tileX, tileY),
+			},
+		})
+		if !ebiten.IsKeyPressed(ebiten.KeyShift) {
+			g.placing = nil
+		}
+	}
 }
 
-func (g *clientGame) updateVisibility() {
-	m := make(map[image.Point]bool, 0)
-	for _, t := range g.screen.tiles {
-		if t.Visible || t.Unit != nil {
-			m[t.Point] = false
+// updateProduction handles selected building, number keys queue units, Backspace cancels the last one, right click sets rally point
+func (g *clientGame) updateProduction() {
+	if g.placing != nil || !ebiten.IsFocused() {
+		return
+	}
+	if inpututil.IsMouseButtonJustPressed(ebiten.MouseButtonLeft) {
+		mx, my := ebiten.CursorPosition()
+		tileX, tileY := g.screenToWorldTiles(mx, my)
+		g.building = nil
+		if t, ok := g.store.GetTile(image.Pt(tileX, tileY)); ok && t.Building != nil && t.Building.Owner == g.playerId {
+			g.building = t.Building
 		}
 	}
-	for _, unit := range g.store.GetUnitsByPlayerId(g.playerId) {
-		for _, vector := range unit.ISee {
-			p := unit.Position.ImagePoint().Add(vector)
-			m[p] = true
+	b := g.building
+	if b == nil {
+		return
+	}
+	if !b.IsAlive() {
+		g.building = nil
+		return
+	}
+	for i, t := range b.Produces {
+		if i < 9 && inpututil.IsKeyJustPressed(ebiten.Key1+ebiten.Key(i)) {
+			g.enDispatch(game.QueueUnitAction{
+				Type: game.QueueUnitActionType,
+				Payload: game.QueueUnitPayload{
+					BuildingId: b.Id,
+					UnitType:   t,
+				},
+			})
 		}
 	}
-	for p, visible := range m {
-		if t, ok := g.screen.tiles[p]; ok {
-			t.Visible = visible
+	if inpututil.IsKeyJustPressed(ebiten.KeyBackspace) && len(b.Queue) > 0 {
+		g.enDispatch(game.QueueCancelAction{
+			Type: game.QueueCancelActionType,
+			Payload: game.QueueCancelPayload{
+				BuildingId: b.Id,
+				UnitId:     b.Queue[len(b.Queue)-1].Unit.Id,
+			},
+		})
+	}
+	if inpututil.IsMouseButtonJustPressed(ebiten.MouseButtonRight) {
+		mx, my := ebiten.CursorPosition()
+		tileX, tileY := g.screenToWorldTiles(mx, my)
+		g.enDispatch(game.SetRallyAction{
+			Type: game.SetRallyActionType,
+			Payload: game.SetRallyPayload{
+				BuildingId: b.Id,
+				Point:      image.Pt(tileX, tileY),
+			},
+		})
+	}
+}
+
+func (g *clientGame) updateVisibility() {
+	viewer := g.playerId
+	if g.spectator {
+		viewer = g.watching
+	}
+	revealAll := g.spectator && viewer == game.ZeroPlayerId
+	if !revealAll {
+		g.fog.Update(g.store, viewer)
+	}
+	for _, t := range g.screen.tiles {
+		if t == nil {
+			continue
 		}
+		if revealAll {
+			t.Visibility = game.Visible
+		} else {
+			t.Visibility = g.fog.Of(t.Point)
+		}
+	}
+}
+
+// enemyAt returns visible unit of enemy player at tile
+func (g *clientGame) enemyAt(p image.Point) *game.Unit {
+	t, ok := g.store.GetTile(p)
+	if !ok || t.Unit == nil || t.Visibility != game.Visible || !game.CanAttack(g.store, g.playerId, t.Unit.Owner) {
+		return nil
+	}
+	return t.Unit
+}
+
+// enemyBuildingAt returns visible building of enemy player at tile
+func (g *clientGame) enemyBuildingAt(p image.Point) *game.Building {
+	t, ok := g.store.GetTile(p)
+	if !ok || t.Building == nil || t.Visibility != game.Visible || !game.CanAttack(g.store, g.playerId, t.Building.Owner) {
+		return nil
+	}
+	return t.Building
+}
+
+// resourceAt returns not depleted resource node at explored tile
+func (g *clientGame) resourceAt(p image.Point) *game.ResourceNode {
+	t, ok := g.store.GetTile(p)
+	if !ok || t.Resource == nil || t.Resource.Amount == 0 {
+		return nil
 	}
+	return t.Resource
 }
 
 func (g *clientGame) screenToWorld(screenX, screenY int) (int, int) {
@@ -0,0 +1,131 @@
+package main
+
+import (
+	"time"
+
+	"github.com/bmcszk/gptrts/pkg/game"
+)
+
+// interpolationMinSamples - authoritative states kept per unit at least, lockstep records a state every tick
+// so buffer grows with delay, see newInterpolator
+const interpolationMinSamples = 8
+
+// interpolationDelay - how far behind the latest state units are drawn, it should exceed time a unit needs
+// to cross a tile, otherwise units wait on every tile for the next step
+var interpolationDelay = 300 * time.Millisecond
+
+type positionSample struct {
+	at       time.Time
+	position game.PF
+}
+
+// interpolator keeps recent authoritative positions of units for rendering, simulation state in store is not changed
+type interpolator struct {
+	delay   time.Duration
+	size    int // states kept per unit, they must cover the delay
+	samples map[game.UnitIdType][]positionSample
+}
+
+func newInterpolator(delay time.Duration) *interpolator {
+	size := int(delay*game.TickRate/time.Second) + 2
+	if size < interpolationMinSamples {
+		size = interpolationMinSamples
+	}
+	return &interpolator{
+		delay:   delay,
+		size:    size,
+		samples: make(map[game.UnitIdType][]positionSample),
+	}
+}
+
+// record adds state of the unit received at time, the oldest one is dropped when buffer is full
+func (ip *interpolator) record(u *game.Unit, at time.Time) {
+	samples := append(ip.samples[u.Id], positionSample{at: at, position: u.Position})
+	if len(samples) > ip.size {
+		samples = samples[len(samples)-ip.size:]
+	}
+	ip.samples[u.Id] = samples
+}
+
+// moved tells whether unit is not at its last recorded position
+func (ip *interpolator) moved(u *game.Unit) bool {
+	samples := ip.samples[u.Id]
+	return len(samples) == 0 || samples[len(samples)-1].position != u.Position
+}
+
+// reset starts buffer of the unit again, e.g. when unit appears or is moved locally
+func (ip *interpolator) reset(u *game.Unit, at time.Time) {
+	ip.samples[u.Id] = []positionSample{{at: at, position: u.Position}}
+}
+
+func (ip *interpolator) forget(unitId game.UnitIdType) {
+	delete(ip.samples, unitId)
+}
+
+func (ip *interpolator) clear() {
+	ip.samples = make(map[game.UnitIdType][]positionSample)
+}
+
+// position returns position of the unit in tiles at now minus delay, it is between the two states around that time
+func (ip *interpolator) position(u *game.Unit, now time.Time) (float64, float64) {
+	samples := ip.samples[u.Id]
+	if ip.delay <= 0 || len(samples) == 0 {
+		return u.Position.Floats()
+	}
+	at := now.Add(-ip.delay)
+	if !at.After(samples[0].at) {
+		return samples[0].position.Floats()
+	}
+	for i := 1; i < len(samples); i++ {
+		prev, next := samples[i-1], samples[i]
+		if at.After(next.at) {
+			continue
+		}
+		t := float64(at.Sub(prev.at)) / float64(next.at.Sub(prev.at))
+		x0, y0 := prev.position.Floats()
+		x1, y1 := next.position.Floats()
+		return x0 + (x1-x0)*t, y0 + (y1-y0)*t
+	}
+	return samples[len(samples)-1].position.Floats()
+}
+
+// updateInterpolation records states of units changed by action from server
+func (g *clientGame) updateInterpolation(action game.Action) {
+	now := time.Now()
+	switch a := action.(type) {
+	case game.PlayerJoinSuccessAction, game.ResyncAction:
+		g.interp.clear()
+	case game.SpawnUnitAction:
+		if u := g.store.GetUnitById(a.Payload.Id); u != nil {
+			g.interp.reset(u, now)
+		}
+	case game.UnitEnteredVisionAction:
+		if u := g.store.GetUnitById(a.Payload.Unit.Id); u != nil {
+			g.interp.reset(u, now)
+		}
+	case game.MoveStepAction:
+		if u := g.store.GetUnitById(a.Payload.UnitId); u != nil {
+			g.interp.record(u, now)
+		}
+	case game.TickAction:
+		// lockstep moves units every tick without steps from server, tick which stops the unit is recorded too
+		for _, u := range g.store.GetAllUnits() {
+			if g.interp.moved(u) {
+				g.interp.record(u, now)
+			}
+		}
+	case game.UnitDiedAction:
+		g.interp.forget(a.Payload.UnitId)
+	case game.UnitLeftVisionAction:
+		g.interp.forget(a.Payload.UnitId)
+	}
+}
+
+// unitPosition returns where unit is drawn in world pixels, units predicted locally are drawn as simulated
+func (g *clientGame) unitPosition(u *game.Unit) (float64, float64) {
+	x, y := u.Position.Floats()
+	if !g.predicted[u.Id] {
+		x, y = g.interp.position(u, time.Now())
+	}
+	return x * tileSize, y * tileSize
+}
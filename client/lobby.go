@@ -0,0 +1,111 @@
+package main
+
+import (
+	"fmt"
+	"image/color"
+	"strings"
+
+	"github.com/bmcszk/gptrts/pkg/game"
+	"github.com/hajimehoshi/ebiten/v2"
+	"github.com/hajimehoshi/ebiten/v2/ebitenutil"
+	"github.com/hajimehoshi/ebiten/v2/inpututil"
+)
+
+// updateLobby handles lobby keys, number keys select slot, C changes color, T changes team, R toggles ready
+func (g *clientGame) updateLobby() {
+	i := g.lobby.SlotOf(g.playerId)
+	if i < 0 || !ebiten.IsFocused() {
+		return
+	}
+	slot := g.lobby.Slots[i]
+	if inpututil.IsKeyJustPressed(ebiten.KeyR) {
+		g.enDispatch(game.ReadyAction{
+			Type: game.ReadyActionType,
+			Payload: game.ReadyPayload{
+				PlayerId: g.playerId,
+				Ready:    !slot.Ready,
+			},
+		})
+	}
+	if slot.Ready {
+		return
+	}
+	for n := range g.lobby.Slots {
+		if n < 9 && n != i && inpututil.IsKeyJustPressed(ebiten.Key1+ebiten.Key(n)) && g.lobby.Slots[n].IsFree() {
+			g.enDispatch(game.SelectSlotAction{
+				Type: game.SelectSlotActionType,
+				Payload: game.SelectSlotPayload{
+					PlayerId: g.playerId,
+					Slot:     n,
+				},
+			})
+		}
+	}
+	if inpututil.IsKeyJustPressed(ebiten.KeyC) {
+		if c, ok := g.nextColor(slot.Color); ok {
+			g.enDispatch(game.SelectColorAction{
+				Type: game.SelectColorActionType,
+				Payload: game.SelectColorPayload{
+					PlayerId: g.playerId,
+					Color:    c,
+				},
+			})
+		}
+	}
+	if inpututil.IsKeyJustPressed(ebiten.KeyT) {
+		g.enDispatch(game.SelectTeamAction{
+			Type: game.SelectTeamActionType,
+			Payload: game.SelectTeamPayload{
+				PlayerId: g.playerId,
+				Team:     slot.Team%len(g.lobby.Slots) + 1,
+			},
+		})
+	}
+}
+
+// nextColor returns color following c in palette which nobody else uses
+func (g *clientGame) nextColor(c color.RGBA) (color.RGBA, bool) {
+	start := 0
+	for i, pc := range game.PlayerColors {
+		if pc == c {
+			start = i + 1
+		}
+	}
+	for i := 0; i < len(game.PlayerColors); i++ {
+		pc := game.PlayerColors[(start+i)%len(game.PlayerColors)]
+		if pc != c && !g.lobby.ColorTaken(pc, g.playerId) {
+			return pc, true
+		}
+	}
+	return c, false
+}
+
+// drawLobby covers the map with slots until the match starts
+func (g *clientGame) drawLobby(enScreen *ebiten.Image) {
+	if g.lobby.IsPlaying() {
+		return
+	}
+	w, h := enScreen.Size()
+	ebitenutil.DrawRect(enScreen, 0, 0, float64(w), float64(h), color.RGBA{0, 0, 0, 224})
+
+	lines := []string{"LOBBY", ""}
+	for i, s := range g.lobby.Slots {
+		name := "(free)"
+		if !s.IsFree() {
+			name = s.Name
+		}
+		ready := ""
+		if s.Ready {
+			ready = "ready"
+		}
+		ebitenutil.DrawRect(enScreen, 16, float64(32+16*len(lines)), 12, 12, s.Color)
+		lines = append(lines, fmt.Sprintf("%d. %-16s team %d  %s", i+1, name, s.Team, ready))
+	}
+	lines = append(lines, "")
+	if g.lobby.Phase == game.LobbyPhaseCountdown {
+		lines = append(lines, fmt.Sprintf("starting in %d", (g.lobby.Countdown+game.TickRate-1)/game.TickRate))
+	} else {
+		lines = append(lines, "1-9: slot  C: color  T: team  R: ready")
+	}
+	ebitenutil.DebugPrintAt(enScreen, strings.Join(lines, "\n"), 32, 32)
+}
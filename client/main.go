@@ -1,8 +1,9 @@
 package main
 
 import (
-	"crypto/md5"
 	"errors"
+	"flag"
+	"fmt"
 	"image"
 	"image/color"
 	"log"
@@ -10,6 +11,7 @@ import (
 	"net/url"
 	"os"
 	"strings"
+	"time"
 
 	"github.com/bmcszk/gptrts/pkg/comm"
 	"github.com/bmcszk/gptrts/pkg/game"
@@ -21,6 +23,9 @@ import (
 const (
 	screenWidth  = 640
 	screenHeight = 480
+
+	reconnectMinDelay = 500 * time.Millisecond
+	reconnectMaxDelay = 30 * time.Second
 )
 
 var (
@@ -65,8 +70,27 @@ func init() {
 }
 
 func main() {
-	name := getName()
-	playerId := getPlayerId(name)
+	roomId := flag.String("room", "", "id of room to join, default room is used when empty")
+	roomName := flag.String("create", "", "create new room with the name and join it")
+	list := flag.Bool("list", false, "print rooms on the server and exit")
+	replay := flag.String("replay", "", "watch replay file recorded by server instead of playing")
+	spectate := flag.Bool("spectate", false, "watch the match without playing")
+	tokenPath := flag.String("token", "", "file with session token issued by server, <name>.token by default")
+	flag.DurationVar(&interpolationDelay, "interp", interpolationDelay, "delay of drawn unit positions behind server updates, 0 disables interpolation")
+	flag.Parse()
+
+	if *replay != "" {
+		watchReplay(*replay)
+		return
+	}
+
+	var name string
+	if !*list {
+		name = getName()
+	}
+	if *tokenPath == "" {
+		*tokenPath = fmt.Sprintf("%s.token", name)
+	}
 	u := url.URL{Scheme: "ws", Host: "localhost:8000", Path: "/ws"}
 	log.Printf("connecting to %s", u.String())
 
@@ -76,33 +100,89 @@ func main() {
 	}
 	defer ws.Close()
 
-	c := newClient(playerId, ws)
+	c := newClient(game.ZeroPlayerId, ws)
 
-	g := newClientGame(playerId, game.NewStoreImpl(), c.processNewAction)
+	// player id is known after authentication
+	g := newClientGame(game.ZeroPlayerId, game.NewStoreImpl(), c.processNewAction)
 	c.game = g
 
+	player := game.Player{
+		Name:  name,
+		Color: nameToColor(name),
+	}
+
 	// Read messages from the server
 	go func() {
-		for c.Connected {
+		for {
 			action, err := c.HandleInMessages()
 			if err != nil {
 				log.Println(err)
+				if !c.IsConnected() {
+					// session is resumed with the token, server sends full state after join
+					c.reconnect(u.String())
+					c.authenticate(name, *tokenPath)
+				}
 				continue
 			}
-			g.HandleAction(action, c.route)
+			switch a := action.(type) {
+			case game.AuthSuccessAction:
+				log.Printf("authenticated as %s %q", uuid.UUID(a.Payload.PlayerId), a.Payload.Name)
+				if err := os.WriteFile(*tokenPath, []byte(a.Payload.Token), 0o600); err != nil {
+					log.Println(err)
+				}
+				c.PlayerId = a.Payload.PlayerId
+				playerId := a.Payload.PlayerId
+				g.apply(func() { g.playerId = playerId })
+				player.Id = a.Payload.PlayerId
+				player.Name = a.Payload.Name
+				switch {
+				case *roomName != "":
+					c.processNewAction(game.RoomCreateAction{
+						Type:    game.RoomCreateActionType,
+						Payload: game.RoomCreatePayload{Name: *roomName},
+					})
+				case *roomId != "":
+					c.processNewAction(game.RoomJoinAction{
+						Type:    game.RoomJoinActionType,
+						Payload: game.RoomJoinPayload{RoomId: game.RoomIdType(*roomId)},
+					})
+				default:
+					c.join(player, *spectate)
+				}
+			case game.AuthFailedAction:
+				log.Fatalf("authentication failed: %s, remove %s to play as new player", a.Payload.Reason, *tokenPath)
+			case game.RoomListSuccessAction:
+				printRooms(a.Payload.Rooms)
+				if *list {
+					os.Exit(0)
+				}
+			case game.RoomJoinedAction:
+				log.Printf("joined room %s %q", a.Payload.Id, a.Payload.Name)
+				// reconnected client returns to the same room
+				*roomId, *roomName = string(a.Payload.Id), ""
+				c.join(player, *spectate)
+			case game.RoomJoinFailedAction:
+				log.Fatalf("cannot join room %s: %s", a.Payload.RoomId, a.Payload.Reason)
+			case game.JoinRejectedAction:
+				log.Fatalf("cannot join match: %s", a.Payload.Reason)
+			case game.ActionRejectedAction:
+				log.Printf("action %s rejected: %s", a.Payload.ActionType, a.Payload.Reason)
+				if a.Payload.ActionType == game.MoveStartActionType {
+					seq := a.Payload.Seq
+					g.apply(func() { g.rejectInput(seq) })
+				}
+			default:
+				g.apply(func() { g.HandleAction(action, c.route) })
+			}
 		}
 	}()
 
-	if err = c.Send(game.PlayerJoinAction{
-		Type: game.PlayerJoinActionType,
-		Payload: game.Player{
-			Id:    playerId,
-			Name:  name,
-			Color: nameToColor(name),
-		},
-	}); err != nil {
-		log.Println(err)
+	if *list {
+		c.processNewAction(game.RoomListAction{Type: game.RoomListActionType})
+		// wait for the room list
+		select {}
 	}
+	c.authenticate(name, *tokenPath)
 
 	// start ebiten on main thread
 	ebiten.SetWindowSize(screenWidth, screenHeight)
@@ -114,6 +194,19 @@ func main() {
 	}
 }
 
+func watchReplay(path string) {
+	v, err := loadReplay(path)
+	if err != nil {
+		log.Fatal(err)
+	}
+	ebiten.SetWindowSize(screenWidth, screenHeight)
+	ebiten.SetWindowResizingMode(ebiten.WindowResizingModeEnabled)
+	ebiten.SetWindowTitle("replay " + path)
+	if err := ebiten.RunGame(v); err != nil {
+		log.Fatal(err)
+	}
+}
+
 func nameToColor(name string) color.RGBA {
 	name = strings.TrimSpace(name)
 	name = strings.ToLower(name)
@@ -146,36 +239,96 @@ func randomRGBA() color.RGBA {
 }
 
 func getName() string {
-	if len(os.Args) < 2 {
+	if flag.NArg() < 1 {
 		log.Fatal(errors.New("agrument missing"))
 	}
-	return os.Args[1]
+	return flag.Arg(0)
 }
 
-func getPlayerId(name string) game.PlayerIdType {
-	hash := md5.Sum([]byte(name))
-	id, err := uuid.FromBytes(hash[:])
-	if err != nil {
-		log.Fatal(err)
+func printRooms(rooms []game.RoomInfo) {
+	for _, r := range rooms {
+		fmt.Printf("%s\t%s\t%d/%d players\n", r.Id, r.Name, r.Players, r.MaxPlayers)
 	}
-	return game.PlayerIdType(id)
 }
 
-// processNewAction - handler of new actions
+// join - spectators get the match without taking part in it
+func (c *client) join(player game.Player, spectate bool) {
+	if !spectate {
+		c.sendPlayerJoin(player)
+		return
+	}
+	if err := c.Send(game.SpectatorJoinAction{
+		Type: game.SpectatorJoinActionType,
+		Payload: game.SpectatorJoinPayload{
+			Id:   player.Id,
+			Name: player.Name,
+		},
+	}); err != nil {
+		log.Println(err)
+	}
+}
+
+// authenticate - handshake with token of earlier session, server issues new identity without it
+func (c *client) authenticate(name, tokenPath string) {
+	token, err := os.ReadFile(tokenPath)
+	if err != nil && !errors.Is(err, os.ErrNotExist) {
+		log.Println(err)
+	}
+	if err := c.Send(game.AuthAction{
+		Type: game.AuthActionType,
+		Payload: game.AuthPayload{
+			Name:  name,
+			Token: strings.TrimSpace(string(token)),
+		},
+	}); err != nil {
+		log.Println(err)
+	}
+}
+
+// reconnect dials server until it answers, delay doubles after every failed attempt
+func (c *client) reconnect(url string) {
+	delay := reconnectMinDelay
+	for {
+		// jitter spreads reconnects of clients dropped at once
+		wait := delay + time.Duration(rand.Int63n(int64(delay/2)))
+		log.Printf("reconnecting to %s in %s", url, wait.Truncate(time.Millisecond))
+		time.Sleep(wait)
+		ws, _, err := websocket.DefaultDialer.Dial(url, nil)
+		if err == nil {
+			c.Reconnect(ws)
+			return
+		}
+		log.Println("dial:", err)
+		delay *= 2
+		if delay > reconnectMaxDelay {
+			delay = reconnectMaxDelay
+		}
+	}
+}
+
+func (c *client) sendPlayerJoin(player game.Player) {
+	if err := c.Send(game.PlayerJoinAction{
+		Type:    game.PlayerJoinActionType,
+		Payload: player,
+	}); err != nil {
+		log.Println(err)
+	}
+}
+
+// processNewAction - handler of new actions, intents are sent to server which owns the simulation
 func (c *client) processNewAction(action game.Action) {
 	if err := c.Send(action); err != nil {
 		log.Println("route %w", err)
 	}
-	c.game.HandleAction(action, c.route)
 }
 
 // route - handler of outgoing actions
 func (c *client) route(action game.Action) {
-	if err := c.Send(action); err != nil {
-		log.Println("route %w", err)
-	}
-	switch a := action.(type) {
-	case game.MoveStartAction, game.MoveStepAction, game.MoveStopAction:
-		c.game.HandleAction(a, c.route)
+	// in lockstep mode every game logic computes consequences of tick bundle by itself
+	if c.game.mode == game.LockstepMode {
+		c.game.HandleAction(action, c.route)
+		return
 	}
+	// state is authoritative on server, local consequences of handled actions are dropped
+	log.Printf("client drop %s", action.GetType())
 }
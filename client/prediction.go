@@ -0,0 +1,194 @@
+package main
+
+import (
+	"image"
+	"log"
+	"time"
+
+	"github.com/bmcszk/gptrts/pkg/game"
+	"github.com/google/uuid"
+)
+
+// predictionTimeout - ticks after which move command without acknowledgement is dropped, e.g. when it got lost
+const predictionTimeout = 2 * game.TickRate
+
+// moveInput is move command sent to server and applied locally before server acknowledged it
+type moveInput struct {
+	Seq    int
+	UnitId game.UnitIdType
+	Point  image.Point
+	Tick   int // local tick when command was given
+}
+
+// moveUnit sends move command, in authoritative mode own unit starts moving at once without waiting for server
+func (g *clientGame) moveUnit(u *game.Unit, p image.Point) {
+	g.seq++
+	g.enDispatch(game.MoveStartAction{
+		Type: game.MoveStartActionType,
+		Payload: game.MoveStartPayload{
+			UnitId: u.Id,
+			Point:  p,
+			Seq:    g.seq,
+		},
+	})
+	if g.mode != game.AuthoritativeMode {
+		return
+	}
+	input := moveInput{Seq: g.seq, UnitId: u.Id, Point: p, Tick: g.localTick}
+	g.inputs = append(g.inputs, input)
+	if !g.predicted[u.Id] {
+		g.confirmed[u.Id] = u.NewMoveStepAction()
+	}
+	g.predicted[u.Id] = true
+	g.applyInput(u, input)
+}
+
+// applyInput plans path of the command locally the same way server does
+func (g *clientGame) applyInput(u *game.Unit, input moveInput) {
+	path, costs, err := game.PlanPath(g.store, u, input.Point)
+	if err != nil {
+		log.Printf("unit %s cannot move to %v: %s", uuid.UUID(u.Id), input.Point, err)
+		return
+	}
+	g.applyLocal(game.MoveStepAction{
+		Type: game.MoveStepActionType,
+		Payload: game.MoveStepPayload{
+			UnitId:   u.Id,
+			Position: u.Position,
+			Path:     path,
+			Costs:    costs,
+			Seq:      input.Seq,
+		},
+	})
+}
+
+// applyLocal handles consequences of predicted movement in local game logic only
+func (g *clientGame) applyLocal(action game.Action) {
+	g.GameLogic.HandleAction(action, g.applyLocal)
+}
+
+// predict moves predicted units one tick ahead of server
+func (g *clientGame) predict() {
+	g.localTick++
+	expired := make([]int, 0)
+	for _, input := range g.inputs {
+		if g.localTick-input.Tick >= predictionTimeout {
+			expired = append(expired, input.Seq)
+		}
+	}
+	for _, seq := range expired {
+		g.rejectInput(seq)
+	}
+	now := time.Now()
+	for id := range g.predicted {
+		u := g.store.GetUnitById(id)
+		if u == nil {
+			g.stopPrediction(id)
+			continue
+		}
+		u.Update(g.applyLocal)
+		// drawn as simulated, interpolation continues from here when prediction ends
+		g.interp.reset(u, now)
+	}
+}
+
+// reconcile applies authoritative step of the unit. Acknowledged commands are dropped, state of the server
+// is applied and commands still not acknowledged are replayed on top of it. Step which prediction has already
+// passed on the same path is ignored, so the unit does not jump back by the latency. Unit is left to server
+// when server reaches the end of its path.
+func (g *clientGame) reconcile(action game.MoveStepAction, dispatch game.DispatchFunc) {
+	step := action.Payload
+	if !g.predicted[step.UnitId] {
+		g.GameLogic.HandleAction(action, dispatch)
+		return
+	}
+	g.confirmed[step.UnitId] = action
+	defer func() {
+		if step.Step >= len(step.Path) && g.lastInput(step.UnitId) == nil {
+			g.stopPrediction(step.UnitId)
+		}
+	}()
+	inputs := g.inputs[:0]
+	for _, input := range g.inputs {
+		if input.Seq > step.Seq {
+			inputs = append(inputs, input)
+		}
+	}
+	g.inputs = inputs
+
+	u := g.store.GetUnitById(step.UnitId)
+	if input := g.lastInput(step.UnitId); input != nil {
+		// rewind and replay, move commands replace each other so the last one is enough
+		g.GameLogic.HandleAction(action, g.applyLocal)
+		if u != nil {
+			g.applyInput(u, *input)
+		}
+		return
+	}
+	if u != nil && u.Seq == step.Seq && samePath(u.Path, step.Path) && u.Step >= step.Step {
+		return
+	}
+	// misprediction, server state wins
+	g.GameLogic.HandleAction(action, dispatch)
+}
+
+// lastInput returns the latest command of the unit not acknowledged by server
+func (g *clientGame) lastInput(unitId game.UnitIdType) *moveInput {
+	for i := len(g.inputs) - 1; i >= 0; i-- {
+		if g.inputs[i].UnitId == unitId {
+			return &g.inputs[i]
+		}
+	}
+	return nil
+}
+
+// rejectInput drops command which server rejected or did not acknowledge in time. Unit without other commands
+// returns to its last state from server.
+func (g *clientGame) rejectInput(seq int) {
+	var unitId game.UnitIdType
+	found := false
+	inputs := g.inputs[:0]
+	for _, input := range g.inputs {
+		if input.Seq == seq {
+			unitId, found = input.UnitId, true
+			continue
+		}
+		inputs = append(inputs, input)
+	}
+	g.inputs = inputs
+	if !found || g.lastInput(unitId) != nil {
+		return
+	}
+	if u := g.store.GetUnitById(unitId); u != nil {
+		if a, ok := g.confirmed[unitId]; ok {
+			g.GameLogic.HandleAction(a, g.applyLocal)
+			g.interp.reset(u, time.Now())
+		}
+	}
+	g.stopPrediction(unitId)
+}
+
+// stopPrediction leaves the unit to server
+func (g *clientGame) stopPrediction(unitId game.UnitIdType) {
+	delete(g.predicted, unitId)
+	delete(g.confirmed, unitId)
+}
+
+// resetPrediction drops local predictions, e.g. when state is replaced by server snapshot
+func (g *clientGame) resetPrediction() {
+	g.inputs = nil
+	g.predicted = make(map[game.UnitIdType]bool)
+	g.confirmed = make(map[game.UnitIdType]game.MoveStepAction)
+}
+
+func samePath(a, b []image.Point) bool {
+	if len(a) != len(b) {
+		return false
+	}
+	for i := range a {
+		if a[i] != b[i] {
+			return false
+		}
+	}
+	return true
+}
@@ -0,0 +1,145 @@
+package main
+
+import (
+	"fmt"
+	"log"
+	"os"
+	"time"
+
+	"github.com/bmcszk/gptrts/pkg/game"
+	"github.com/hajimehoshi/ebiten/v2"
+	"github.com/hajimehoshi/ebiten/v2/ebitenutil"
+	"github.com/hajimehoshi/ebiten/v2/inpututil"
+)
+
+const (
+	replaySeekStep = 10 * time.Second
+	replayMinSpeed = 0.25
+	replayMaxSpeed = 8
+)
+
+// replayViewer plays recorded match in local game logic, whole map is visible
+type replayViewer struct {
+	*clientGame
+	header  game.ReplayHeader
+	entries []game.ReplayEntry
+	next    int           // index of the next entry to apply
+	at      time.Duration // replay time
+	speed   float64
+	paused  bool
+}
+
+func loadReplay(path string) (*replayViewer, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+	header, entries, err := game.ReadReplay(f)
+	if header == nil {
+		return nil, err
+	}
+	if err != nil {
+		// recording was interrupted, play what is there
+		log.Printf("replay %s truncated after %d actions: %s", path, len(entries), err)
+	}
+	v := &replayViewer{
+		header:  *header,
+		entries: entries,
+		speed:   1,
+	}
+	v.reset()
+	return v, nil
+}
+
+// reset starts replay from the beginning with fresh state, camera stays where it is
+func (v *replayViewer) reset() {
+	g := newClientGame(game.ZeroPlayerId, game.NewStoreImpl(), func(game.Action) {})
+	g.mode = v.header.Mode
+	g.defs = v.header.Definitions
+	g.lobby = v.header.Lobby
+	g.spectator = true
+	if v.clientGame != nil {
+		g.cameraX, g.cameraY = v.clientGame.cameraX, v.clientGame.cameraY
+		g.watching = v.clientGame.watching
+	}
+	g.Restore(v.header.Snapshot)
+	v.clientGame = g
+	v.next = 0
+	v.at = 0
+}
+
+// dispatch - in lockstep mode replay holds only tick bundles, their consequences are computed locally
+func (v *replayViewer) dispatch(action game.Action) {
+	if v.mode == game.LockstepMode {
+		v.HandleAction(action, v.dispatch)
+	}
+}
+
+// seek moves replay to time, going back replays everything from the beginning
+func (v *replayViewer) seek(at time.Duration) {
+	if at < 0 {
+		at = 0
+	}
+	if at > v.duration() {
+		at = v.duration()
+	}
+	if at < v.at {
+		v.reset()
+	}
+	v.at = at
+	v.play()
+}
+
+// play applies entries recorded until current replay time
+func (v *replayViewer) play() {
+	for v.next < len(v.entries) && v.entries[v.next].At <= v.at {
+		v.HandleAction(v.entries[v.next].Action, v.dispatch)
+		v.next++
+	}
+}
+
+func (v *replayViewer) duration() time.Duration {
+	if len(v.entries) == 0 {
+		return 0
+	}
+	return v.entries[len(v.entries)-1].At
+}
+
+// Update handles replay keys, space pauses, comma and period seek, minus and equal change speed, Home restarts
+func (v *replayViewer) Update() error {
+	v.updateCamera()
+	v.updateSpectator()
+	if ebiten.IsFocused() {
+		switch {
+		case inpututil.IsKeyJustPressed(ebiten.KeySpace):
+			v.paused = !v.paused
+		case inpututil.IsKeyJustPressed(ebiten.KeyComma):
+			v.seek(v.at - replaySeekStep)
+		case inpututil.IsKeyJustPressed(ebiten.KeyPeriod):
+			v.seek(v.at + replaySeekStep)
+		case inpututil.IsKeyJustPressed(ebiten.KeyHome):
+			v.seek(0)
+		case inpututil.IsKeyJustPressed(ebiten.KeyMinus) && v.speed > replayMinSpeed:
+			v.speed /= 2
+		case inpututil.IsKeyJustPressed(ebiten.KeyEqual) && v.speed < replayMaxSpeed:
+			v.speed *= 2
+		}
+	}
+	if !v.paused && v.at < v.duration() {
+		v.seek(v.at + time.Duration(v.speed*float64(time.Second)/float64(ebiten.TPS())))
+	}
+	return nil
+}
+
+func (v *replayViewer) Draw(enScreen *ebiten.Image) {
+	v.clientGame.Draw(enScreen)
+	state := "playing"
+	if v.paused {
+		state = "paused"
+	}
+	status := fmt.Sprintf("REPLAY %s / %s  x%g  %s\nspace: pause  ,/.: seek  -/=: speed  home: restart",
+		v.at.Truncate(time.Second), v.duration().Truncate(time.Second), v.speed, state)
+	_, h := enScreen.Size()
+	ebitenutil.DebugPrintAt(enScreen, status, 0, h-48)
+}
@@ -16,25 +16,30 @@ const (
 	tileSpriteSize = 16
 	tileSpriteXNum = 7
 	selectedBorder = 2
+	hpBarHeight    = 3
 )
 
 type screen struct {
 	rect  image.Rectangle
 	tiles map[image.Point]*game.Tile
 	units map[*game.Unit]bool
+	// buildings with visibility of any of their tiles
+	buildings map[*game.Building]bool
 }
 
 var emptyScreen = screen{
-	rect:  image.Rectangle{},
-	tiles: make(map[image.Point]*game.Tile),
-	units: make(map[*game.Unit]bool),
+	rect:      image.Rectangle{},
+	tiles:     make(map[image.Point]*game.Tile),
+	units:     make(map[*game.Unit]bool),
+	buildings: make(map[*game.Building]bool),
 }
 
 func newScreen(rect image.Rectangle, tiles map[image.Point]*game.Tile) *screen {
 	return &screen{
-		rect:  rect,
-		tiles: tiles,
-		units: make(map[*game.Unit]bool, 0),
+		rect:      rect,
+		tiles:     tiles,
+		units:     make(map[*game.Unit]bool, 0),
+		buildings: make(map[*game.Building]bool, 0),
 	}
 }
 
@@ -42,18 +47,45 @@ func (s *screen) is(rect image.Rectangle) bool {
 	return s.rect.Eq(rect)
 }
 
-func (s *screen) draw(enScreen *ebiten.Image, cameraX, cameraY int) {
+// draw - map, ghosts are last seen buildings of other players on explored tiles, units are drawn at position
+// in world pixels returned by unitPosition
+func (s *screen) draw(enScreen *ebiten.Image, cameraX, cameraY int, ghosts []game.Building, unitPosition func(*game.Unit) (float64, float64)) {
+	for b := range s.buildings {
+		s.buildings[b] = false
+	}
 	for _, t := range s.tiles {
 		if t != nil {
 			drawTile(t, enScreen, cameraX, cameraY)
 			if t.Unit != nil {
-				s.units[t.Unit] = t.Visible
+				s.units[t.Unit] = t.Visibility == game.Visible
+			}
+			if t.Building != nil {
+				s.buildings[t.Building] = s.buildings[t.Building] || t.Visibility == game.Visible
 			}
 		}
 	}
+	for _, ghost := range ghosts {
+		if ghost.Rect().Overlaps(s.rect) {
+			drawGhost(ghost, enScreen, cameraX, cameraY)
+		}
+	}
+	for b, visible := range s.buildings {
+		if !b.IsAlive() {
+			delete(s.buildings, b)
+			continue
+		}
+		if visible {
+			drawBuilding(b, enScreen, cameraX, cameraY)
+		}
+	}
 	for u, visible := range s.units {
+		if !u.IsAlive() {
+			delete(s.units, u)
+			continue
+		}
 		if visible {
-			drawUnit(u, enScreen, cameraX, cameraY)
+			x, y := unitPosition(u)
+			drawUnit(u, x, y, enScreen, cameraX, cameraY)
 		}
 	}
 }
@@ -61,9 +93,15 @@ func (s *screen) draw(enScreen *ebiten.Image, cameraX, cameraY int) {
 func drawTile(t *game.Tile, enScreen *ebiten.Image, cameraX, cameraY int) {
 	p := t.Point
 
+	if t.Visibility == game.Unexplored {
+		x, y := float64(p.X*tileSize-cameraX), float64(p.Y*tileSize-cameraY)
+		ebitenutil.DrawRect(enScreen, x, y, tileSize, tileSize, color.Black)
+		return
+	}
+
 	op := &ebiten.DrawImageOptions{}
 
-	if !t.Visible {
+	if t.Visibility == game.Explored {
 		// Create a new color matrix and set the brightness to a lower value
 		cm := ebiten.ColorM{}
 		cm.Scale(0.5, 0.5, 0.5, 1.0) // Make the tile darker
@@ -80,10 +118,9 @@ func drawTile(t *game.Tile, enScreen *ebiten.Image, cameraX, cameraY int) {
 	enScreen.DrawImage(tilesImage.SubImage(image.Rect(sx, sy, sx+tileSpriteSize, sy+tileSpriteSize)).(*ebiten.Image), op)
 }
 
-func drawUnit(u *game.Unit, enScreen *ebiten.Image, cameraX, cameraY int) {
-	screenPosition := u.Position.Mul(tileSize)
-	x := screenPosition.X - float64(cameraX)
-	y := screenPosition.Y - float64(cameraY)
+func drawUnit(u *game.Unit, x, y float64, enScreen *ebiten.Image, cameraX, cameraY int) {
+	x -= float64(cameraX)
+	y -= float64(cameraY)
 
 	if u.Selected {
 		col := color.RGBA{0, 255, 0, 255}
@@ -91,6 +128,42 @@ func drawUnit(u *game.Unit, enScreen *ebiten.Image, cameraX, cameraY int) {
 	}
 
 	ebitenutil.DrawRect(enScreen, x, y, float64(u.Size.X), float64(u.Size.Y), u.Color)
+
+	if u.HP < u.MaxHP {
+		w := float64(u.Size.X)
+		ebitenutil.DrawRect(enScreen, x, y-hpBarHeight, w, hpBarHeight, color.RGBA{255, 0, 0, 255})
+		ebitenutil.DrawRect(enScreen, x, y-hpBarHeight, w*float64(u.HP)/float64(u.MaxHP), hpBarHeight, color.RGBA{0, 255, 0, 255})
+	}
+}
+
+func drawBuilding(b *game.Building, enScreen *ebiten.Image, cameraX, cameraY int) {
+	x := float64(b.Position.X*tileSize - cameraX)
+	y := float64(b.Position.Y*tileSize - cameraY)
+	w := float64(b.Footprint.X * tileSize)
+	h := float64(b.Footprint.Y * tileSize)
+
+	col := b.Color
+	if !b.IsComplete() {
+		// construction site is drawn translucent, color is alpha premultiplied
+		col = color.RGBA{col.R / 2, col.G / 2, col.B / 2, col.A / 2}
+	}
+	ebitenutil.DrawRect(enScreen, x, y, w, h, col)
+
+	if !b.IsComplete() {
+		ebitenutil.DrawRect(enScreen, x, y+h-hpBarHeight, w, hpBarHeight, color.RGBA{64, 64, 64, 255})
+		ebitenutil.DrawRect(enScreen, x, y+h-hpBarHeight, w*float64(b.Progress)/float64(b.BuildTime), hpBarHeight, color.RGBA{255, 255, 0, 255})
+	}
+
+	if b.HP < b.MaxHP {
+		ebitenutil.DrawRect(enScreen, x, y-hpBarHeight, w, hpBarHeight, color.RGBA{255, 0, 0, 255})
+		ebitenutil.DrawRect(enScreen, x, y-hpBarHeight, w*float64(b.HP)/float64(b.MaxHP), hpBarHeight, color.RGBA{0, 255, 0, 255})
+	}
+}
+
+// drawGhost draws building as it was last seen, darker like explored tiles
+func drawGhost(b game.Building, enScreen *ebiten.Image, cameraX, cameraY int) {
+	b.Color = color.RGBA{b.Color.R / 2, b.Color.G / 2, b.Color.B / 2, b.Color.A}
+	drawBuilding(&b, enScreen, cameraX, cameraY)
 }
 
 func getBackgroundColorImage(className string) *ebiten.Image {
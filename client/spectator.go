@@ -0,0 +1,51 @@
+package main
+
+import (
+	"bytes"
+	"fmt"
+	"sort"
+
+	"github.com/bmcszk/gptrts/pkg/game"
+	"github.com/hajimehoshi/ebiten/v2"
+	"github.com/hajimehoshi/ebiten/v2/ebitenutil"
+	"github.com/hajimehoshi/ebiten/v2/inpututil"
+)
+
+// updateSpectator handles V key, it switches vision between whole map and vision of every player
+func (g *clientGame) updateSpectator() {
+	if !ebiten.IsFocused() || !inpututil.IsKeyJustPressed(ebiten.KeyV) {
+		return
+	}
+	players := g.store.GetAllPlayers()
+	sort.Slice(players, func(i, j int) bool {
+		return bytes.Compare(players[i].Id[:], players[j].Id[:]) < 0
+	})
+	next := game.ZeroPlayerId
+	for i, p := range players {
+		if g.watching == game.ZeroPlayerId {
+			next = p.Id
+			break
+		}
+		if p.Id == g.watching && i+1 < len(players) {
+			next = players[i+1].Id
+			break
+		}
+	}
+	g.watching = next
+	// explored tiles of other player are not known
+	g.fog = game.NewFog()
+	g.updateVisibility()
+}
+
+// drawSpectator prints whose vision spectator sees
+func (g *clientGame) drawSpectator(enScreen *ebiten.Image) {
+	if !g.spectator {
+		return
+	}
+	vision := "whole map"
+	if p, ok := g.store.GetPlayer(g.watching); ok {
+		vision = p.Name
+	}
+	_, h := enScreen.Size()
+	ebitenutil.DebugPrintAt(enScreen, fmt.Sprintf("SPECTATING %s  V: switch vision", vision), 0, h-16)
+}
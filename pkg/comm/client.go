@@ -12,31 +12,45 @@ import (
 
 type Client struct {
 	ws        *websocket.Conn
-	Connected bool
+	connected bool
 	PlayerId  game.PlayerIdType
-	mux       sync.Mutex
+	Name      string     // player name, on server set with PlayerId by authentication
+	mux       sync.Mutex // guards ws and connected, read loop and senders run in different goroutines
 }
 
 func NewClient(ws *websocket.Conn) *Client {
 	c := &Client{
 		ws:        ws,
-		Connected: true,
+		connected: true,
 		mux:       sync.Mutex{},
 	}
 	return c
 }
 
+// IsConnected tells whether connection is open, it is closed by read error and opened again by Reconnect
+func (c *Client) IsConnected() bool {
+	c.mux.Lock()
+	defer c.mux.Unlock()
+	return c.connected
+}
+
 func (c *Client) HandleInMessages() (game.Action, error) {
-	msgType, bytes, err := c.ws.ReadMessage()
-	if msgType == websocket.CloseMessage || (err != nil && err == websocket.ErrCloseSent) {
-		if err := c.ws.Close(); err != nil {
+	c.mux.Lock()
+	ws := c.ws
+	c.mux.Unlock()
+	msgType, bytes, err := ws.ReadMessage()
+	// read errors are permanent, connection is gone
+	if msgType == websocket.CloseMessage || err != nil {
+		if err := ws.Close(); err != nil {
 			log.Println(err)
 		}
-		c.Connected = false
-		log.Printf("player %s connection closed", c.PlayerId)
-		return game.GenericAction[any]{}, err
-	} else if err != nil {
-		log.Println(err)
+		c.mux.Lock()
+		// connection may have been replaced meanwhile
+		if c.ws == ws {
+			c.connected = false
+		}
+		c.mux.Unlock()
+		log.Printf("player %s connection closed", uuid.UUID(c.PlayerId))
 		return game.GenericAction[any]{}, err
 	}
 	action, err := game.UnmarshalAction(bytes)
@@ -48,12 +62,20 @@ func (c *Client) HandleInMessages() (game.Action, error) {
 	return action, nil
 }
 
+// Reconnect replaces dropped connection, identity of the client stays
+func (c *Client) Reconnect(ws *websocket.Conn) {
+	c.mux.Lock()
+	defer c.mux.Unlock()
+	c.ws = ws
+	c.connected = true
+}
+
 func (c *Client) Send(action game.Action) error {
-	if !c.Connected {
-		return nil
-	}
 	c.mux.Lock()
 	defer c.mux.Unlock()
+	if !c.connected {
+		return nil
+	}
 	log.Printf("player %s sending %s", uuid.UUID(c.PlayerId), action.GetType())
 	if err := c.ws.WriteJSON(action); err != nil {
 		return fmt.Errorf("write %w", err)
@@ -4,6 +4,7 @@ import (
 	"encoding/json"
 	"errors"
 	"image"
+	"image/color"
 
 	"github.com/bmcszk/gptrts/pkg/world"
 )
@@ -11,14 +12,73 @@ import (
 type ActionType string
 
 const (
-	PlayerJoinActionType        ActionType = "PlayerJoin"
-	PlayerJoinSuccessActionType ActionType = "PlayerJoinSuccess"
-	SpawnUnitActionType         ActionType = "SpawnUnit"
-	MoveStartActionType         ActionType = "MoveStart"
-	MoveStepActionType          ActionType = "MoveStep"
-	MoveStopActionType          ActionType = "MoveStop"
-	MapLoadActionType           ActionType = "MapLoad"
-	MapLoadSuccessActionType    ActionType = "MapLoadSuccess"
+	PlayerJoinActionType             ActionType = "PlayerJoin"
+	PlayerJoinSuccessActionType      ActionType = "PlayerJoinSuccess"
+	SpawnUnitActionType              ActionType = "SpawnUnit"
+	MoveStartActionType              ActionType = "MoveStart"
+	MoveStepActionType               ActionType = "MoveStep"
+	MoveStopActionType               ActionType = "MoveStop"
+	MapLoadActionType                ActionType = "MapLoad"
+	MapLoadSuccessActionType         ActionType = "MapLoadSuccess"
+	TickActionType                   ActionType = "Tick"
+	StateChecksumActionType          ActionType = "StateChecksum"
+	DesyncActionType                 ActionType = "Desync"
+	AttackActionType                 ActionType = "Attack"
+	TargetSetActionType              ActionType = "TargetSet"
+	UnitDamagedActionType            ActionType = "UnitDamaged"
+	UnitDiedActionType               ActionType = "UnitDied"
+	GatherStartActionType            ActionType = "GatherStart"
+	GatherSetActionType              ActionType = "GatherSet"
+	ResourceGatheredActionType       ActionType = "ResourceGathered"
+	ResourceDeliveredActionType      ActionType = "ResourceDelivered"
+	PlayerResourcesUpdatedActionType ActionType = "PlayerResourcesUpdated"
+	PlaceBuildingActionType          ActionType = "PlaceBuilding"
+	BuildingPlacedActionType         ActionType = "BuildingPlaced"
+	BuildingProgressActionType       ActionType = "BuildingProgress"
+	BuildingDamagedActionType        ActionType = "BuildingDamaged"
+	BuildingDestroyedActionType      ActionType = "BuildingDestroyed"
+	QueueUnitActionType              ActionType = "QueueUnit"
+	QueueCancelActionType            ActionType = "QueueCancel"
+	UnitQueuedActionType             ActionType = "UnitQueued"
+	UnitDequeuedActionType           ActionType = "UnitDequeued"
+	ProductionProgressActionType     ActionType = "ProductionProgress"
+	SetRallyActionType               ActionType = "SetRally"
+	RallySetActionType               ActionType = "RallySet"
+	PlayerDefeatedActionType         ActionType = "PlayerDefeated"
+	GameOverActionType               ActionType = "GameOver"
+	RoomListActionType               ActionType = "RoomList"
+	RoomListSuccessActionType        ActionType = "RoomListSuccess"
+	RoomCreateActionType             ActionType = "RoomCreate"
+	RoomJoinActionType               ActionType = "RoomJoin"
+	RoomJoinedActionType             ActionType = "RoomJoined"
+	RoomJoinFailedActionType         ActionType = "RoomJoinFailed"
+	RoomLeaveActionType              ActionType = "RoomLeave"
+	LobbyStateActionType             ActionType = "LobbyState"
+	SelectSlotActionType             ActionType = "SelectSlot"
+	SelectColorActionType            ActionType = "SelectColor"
+	SelectTeamActionType             ActionType = "SelectTeam"
+	ReadyActionType                  ActionType = "Ready"
+	JoinRejectedActionType           ActionType = "JoinRejected"
+	SetDiplomacyActionType           ActionType = "SetDiplomacy"
+	DiplomacyChangedActionType       ActionType = "DiplomacyChanged"
+	UnitEnteredVisionActionType      ActionType = "UnitEnteredVision"
+	UnitLeftVisionActionType         ActionType = "UnitLeftVision"
+	SaveMatchActionType              ActionType = "SaveMatch"
+	MatchSavedActionType             ActionType = "MatchSaved"
+	MatchSaveFailedActionType        ActionType = "MatchSaveFailed"
+	SpectatorJoinActionType          ActionType = "SpectatorJoin"
+	AuthActionType                   ActionType = "Auth"
+	AuthSuccessActionType            ActionType = "AuthSuccess"
+	AuthFailedActionType             ActionType = "AuthFailed"
+	ActionRejectedActionType         ActionType = "ActionRejected"
+	ResyncActionType                 ActionType = "Resync"
+)
+
+type NetworkMode string
+
+const (
+	AuthoritativeMode NetworkMode = "authoritative" // server simulates and broadcasts state
+	LockstepMode      NetworkMode = "lockstep"      // every game logic simulates tick bundles from server
 )
 
 type Action interface {
@@ -26,6 +86,25 @@ type Action interface {
 	GetPayload() any
 }
 
+// Actions is a list of actions of various types, e.g. commands of one tick
+type Actions []Action
+
+func (a *Actions) UnmarshalJSON(bytes []byte) error {
+	var raws []json.RawMessage
+	if err := json.Unmarshal(bytes, &raws); err != nil {
+		return err
+	}
+	*a = make(Actions, 0, len(raws))
+	for _, raw := range raws {
+		action, err := UnmarshalAction(raw)
+		if err != nil {
+			return err
+		}
+		*a = append(*a, action)
+	}
+	return nil
+}
+
 type GenericAction[T any] struct {
 	Type    ActionType
 	Payload T
@@ -44,9 +123,17 @@ type PlayerJoinAction = GenericAction[Player]
 type PlayerJoinSuccessAction = GenericAction[PlayerJoinSuccessPayload]
 
 type PlayerJoinSuccessPayload struct {
-	PlayerId PlayerIdType
-	Units    []Unit
-	Players  []Player
+	PlayerId    PlayerIdType
+	Units       []Unit
+	Buildings   []Building
+	Players     []Player
+	Definitions *Definitions
+	Stockpiles  []PlayerResourcesPayload
+	Mode        NetworkMode
+	Tick        int
+	Result      *GameOverPayload // set when the match is over
+	Lobby       Lobby
+	Spectator   bool // joined as spectator, snapshot has all units
 }
 
 type SpawnUnitAction = GenericAction[Unit]
@@ -56,6 +143,7 @@ type MoveStartAction = GenericAction[MoveStartPayload]
 type MoveStartPayload struct {
 	UnitId UnitIdType
 	Point  image.Point
+	Seq    int // sequence number of the command given by client, it is acknowledged in MoveStepPayload
 }
 
 type MoveStepAction = GenericAction[MoveStepPayload]
@@ -64,7 +152,9 @@ type MoveStepPayload struct {
 	UnitId   UnitIdType
 	Position PF
 	Path     []image.Point
+	Costs    []int
 	Step     int
+	Seq      int // last move command of the owner applied to the unit
 }
 
 type MoveStopAction = GenericAction[UnitIdType]
@@ -95,7 +185,406 @@ type MapLoadSuccessAction = GenericAction[MapLoadSuccessPayload]
 
 type MapLoadSuccessPayload struct {
 	world.WorldResponse
+	PlayerId      PlayerIdType
+	ResourceNodes []ResourceNode // state of nodes on the tiles, used only for nodes not known yet
+}
+
+type TickAction = GenericAction[TickPayload]
+
+type TickPayload struct {
+	Tick    int
+	Actions Actions
+}
+
+type StateChecksumAction = GenericAction[StateChecksumPayload]
+
+type StateChecksumPayload struct {
+	PlayerId PlayerIdType
+	Tick     int
+	Checksum uint64
+}
+
+type DesyncAction = GenericAction[DesyncPayload]
+
+type DesyncPayload struct {
+	PlayerId PlayerIdType
+	Tick     int
+	Expected uint64
+	Actual   uint64
+}
+
+// AttackAction - player orders unit to attack other unit or building
+type AttackAction = GenericAction[AttackPayload]
+
+type AttackPayload struct {
+	UnitId     UnitIdType
+	TargetId   UnitIdType
+	BuildingId BuildingIdType
+}
+
+// TargetSetAction - unit starts attacking target, zero target ids cancel attack
+type TargetSetAction = GenericAction[TargetPayload]
+
+type TargetPayload struct {
+	UnitId     UnitIdType
+	TargetId   UnitIdType
+	BuildingId BuildingIdType
+}
+
+type UnitDamagedAction = GenericAction[UnitDamagedPayload]
+
+type UnitDamagedPayload struct {
+	UnitId     UnitIdType
+	AttackerId UnitIdType
+	Damage     int
+	HP         int
+}
+
+type UnitDiedAction = GenericAction[UnitDiedPayload]
+
+type UnitDiedPayload struct {
+	UnitId   UnitIdType
+	KillerId UnitIdType
+}
+
+// GatherStartAction - player orders worker to gather resource node at point
+type GatherStartAction = GenericAction[GatherStartPayload]
+
+type GatherStartPayload struct {
+	UnitId UnitIdType
+	Point  image.Point
+}
+
+// GatherSetAction - worker starts gathering, nil point stops gathering
+type GatherSetAction = GenericAction[GatherSetPayload]
+
+type GatherSetPayload struct {
+	UnitId  UnitIdType
+	Point   *image.Point
+	Node    *ResourceNode // current state of the node, it replaces state known by game logic
+	Dropoff image.Point
+}
+
+type ResourceGatheredAction = GenericAction[ResourceGatheredPayload]
+
+type ResourceGatheredPayload struct {
+	UnitId UnitIdType
+	Node   ResourceNode // node state after gathering
+	Amount int
+}
+
+type ResourceDeliveredAction = GenericAction[ResourceDeliveredPayload]
+
+type ResourceDeliveredPayload struct {
+	PlayerId PlayerIdType
+	UnitId   UnitIdType
+	Type     ResourceType
+	Amount   int
+}
+
+type PlayerResourcesUpdatedAction = GenericAction[PlayerResourcesPayload]
+
+type PlayerResourcesPayload struct {
+	PlayerId  PlayerIdType
+	Resources Resources
+}
+
+// PlaceBuildingAction - player orders construction of building at point
+type PlaceBuildingAction = GenericAction[PlaceBuildingPayload]
+
+type PlaceBuildingPayload struct {
+	PlayerId PlayerIdType
+	Type     BuildingTypeIdType
+	Point    image.Point // top left tile
+}
+
+type BuildingPlacedAction = GenericAction[Building]
+
+type BuildingProgressAction = GenericAction[BuildingProgressPayload]
+
+type BuildingProgressPayload struct {
+	BuildingId BuildingIdType
+	Progress   int
+}
+
+type BuildingDamagedAction = GenericAction[BuildingDamagedPayload]
+
+type BuildingDamagedPayload struct {
+	BuildingId BuildingIdType
+	AttackerId UnitIdType
+	Damage     int
+	HP         int
+}
+
+type BuildingDestroyedAction = GenericAction[BuildingDestroyedPayload]
+
+type BuildingDestroyedPayload struct {
+	BuildingId BuildingIdType
+	KillerId   UnitIdType
+}
+
+// QueueUnitAction - player orders production of unit in building
+type QueueUnitAction = GenericAction[QueueUnitPayload]
+
+type QueueUnitPayload struct {
+	BuildingId BuildingIdType
+	UnitType   UnitTypeIdType
+}
+
+// QueueCancelAction - player cancels queued unit, its cost is refunded
+type QueueCancelAction = GenericAction[QueueCancelPayload]
+
+type QueueCancelPayload struct {
+	BuildingId BuildingIdType
+	UnitId     UnitIdType
+}
+
+type UnitQueuedAction = GenericAction[UnitQueuedPayload]
+
+type UnitQueuedPayload struct {
+	BuildingId BuildingIdType
+	Item       QueueItem
+}
+
+// UnitDequeuedAction - queued unit is cancelled or produced
+type UnitDequeuedAction = GenericAction[UnitDequeuedPayload]
+
+type UnitDequeuedPayload struct {
+	BuildingId BuildingIdType
+	UnitId     UnitIdType
+}
+
+// ProductionProgressAction - progress of the first unit in queue
+type ProductionProgressAction = GenericAction[ProductionProgressPayload]
+
+type ProductionProgressPayload struct {
+	BuildingId BuildingIdType
+	Progress   int
+}
+
+// SetRallyAction - player moves rally point of building
+type SetRallyAction = GenericAction[SetRallyPayload]
+
+type SetRallyPayload struct {
+	BuildingId BuildingIdType
+	Point      image.Point
+}
+
+type RallySetAction = GenericAction[RallySetPayload]
+
+type RallySetPayload struct {
+	BuildingId BuildingIdType
+	Point      image.Point
+}
+
+type PlayerDefeatedAction = GenericAction[PlayerDefeatedPayload]
+
+type PlayerDefeatedPayload struct {
+	PlayerId PlayerIdType
+	Reason   VictoryCondition
+}
+
+// GameOverAction - final result of the match, no winners means draw
+type GameOverAction = GenericAction[GameOverPayload]
+
+type GameOverPayload struct {
+	Winners []PlayerIdType
+	Reason  VictoryCondition
+	Tick    int
+	Scores  []PlayerScore
+}
+
+type RoomIdType string
+
+// RoomInfo describes match running on the server
+type RoomInfo struct {
+	Id         RoomIdType
+	Name       string
+	Players    int
+	MaxPlayers int
+}
+
+// RoomListAction - client asks for rooms on the server
+type RoomListAction = GenericAction[RoomListPayload]
+
+type RoomListPayload struct{}
+
+type RoomListSuccessAction = GenericAction[RoomListSuccessPayload]
+
+type RoomListSuccessPayload struct {
+	Rooms []RoomInfo
+}
+
+// RoomCreateAction - client opens new room and joins it
+type RoomCreateAction = GenericAction[RoomCreatePayload]
+
+type RoomCreatePayload struct {
+	Name string
+}
+
+// RoomJoinAction - client enters room, PlayerJoinAction follows
+type RoomJoinAction = GenericAction[RoomJoinPayload]
+
+type RoomJoinPayload struct {
+	RoomId RoomIdType
+}
+
+type RoomJoinedAction = GenericAction[RoomInfo]
+
+type RoomJoinFailedAction = GenericAction[RoomJoinFailedPayload]
+
+type RoomJoinFailedPayload struct {
+	RoomId RoomIdType
+	Reason string
+}
+
+// RoomLeaveAction - client leaves current room, units of the player stay in the match
+type RoomLeaveAction = GenericAction[RoomLeavePayload]
+
+type RoomLeavePayload struct{}
+
+// JoinRejectedAction - player cannot join the match, e.g. all slots of the map are taken
+type JoinRejectedAction = GenericAction[JoinRejectedPayload]
+
+type JoinRejectedPayload struct {
+	PlayerId PlayerIdType
+	Reason   string
+}
+
+// LobbyStateAction - slots and phase of the match, sent to everybody in room on every change
+type LobbyStateAction = GenericAction[Lobby]
+
+type SelectSlotAction = GenericAction[SelectSlotPayload]
+
+type SelectSlotPayload struct {
+	PlayerId PlayerIdType
+	Slot     int
+}
+
+type SelectColorAction = GenericAction[SelectColorPayload]
+
+type SelectColorPayload struct {
+	PlayerId PlayerIdType
+	Color    color.RGBA
+}
+
+type SelectTeamAction = GenericAction[SelectTeamPayload]
+
+type SelectTeamPayload struct {
+	PlayerId PlayerIdType
+	Team     int
+}
+
+// ReadyAction - player toggles readiness, match starts when all players are ready
+type ReadyAction = GenericAction[ReadyPayload]
+
+type ReadyPayload struct {
+	PlayerId PlayerIdType
+	Ready    bool
+}
+
+// SetDiplomacyAction - player changes stance towards other player
+type SetDiplomacyAction = GenericAction[SetDiplomacyPayload]
+
+type SetDiplomacyPayload struct {
+	PlayerId PlayerIdType
+	TargetId PlayerIdType
+	Stance   Stance
+}
+
+type DiplomacyChangedAction = GenericAction[DiplomacyChangedPayload]
+
+type DiplomacyChangedPayload struct {
+	PlayerId PlayerIdType
+	TargetId PlayerIdType
+	Stance   Stance
+}
+
+// UnitEnteredVisionAction - unit became visible to player, it carries full state of the unit
+type UnitEnteredVisionAction = GenericAction[UnitEnteredVisionPayload]
+
+type UnitEnteredVisionPayload struct {
+	PlayerId PlayerIdType
+	Unit     Unit
+}
+
+// UnitLeftVisionAction - unit is not visible to player anymore
+type UnitLeftVisionAction = GenericAction[UnitLeftVisionPayload]
+
+type UnitLeftVisionPayload struct {
 	PlayerId PlayerIdType
+	UnitId   UnitIdType
+}
+
+// SaveMatchAction - admin asks server to save snapshot of the match to a file
+type SaveMatchAction = GenericAction[SaveMatchPayload]
+
+type SaveMatchPayload struct {
+	PlayerId PlayerIdType
+}
+
+type MatchSavedAction = GenericAction[MatchSavedPayload]
+
+type MatchSavedPayload struct {
+	File string // path on the server, see -load flag
+	Tick int
+}
+
+type MatchSaveFailedAction = GenericAction[MatchSaveFailedPayload]
+
+type MatchSaveFailedPayload struct {
+	Reason string
+}
+
+// SpectatorJoinAction - client joins to watch the match, it gets PlayerJoinSuccessAction and all broadcasts
+type SpectatorJoinAction = GenericAction[SpectatorJoinPayload]
+
+type SpectatorJoinPayload struct {
+	Id   PlayerIdType // identifies connection of spectator, it owns nothing
+	Name string
+}
+
+// AuthAction - handshake of new connection, player is identified by token issued before or gets a new identity
+type AuthAction = GenericAction[AuthPayload]
+
+type AuthPayload struct {
+	Name  string
+	Token string // empty for new player
+}
+
+// AuthSuccessAction - identity of the connection, client keeps the token for next connections
+type AuthSuccessAction = GenericAction[AuthSuccessPayload]
+
+type AuthSuccessPayload struct {
+	PlayerId PlayerIdType
+	Name     string
+	Token    string
+}
+
+type AuthFailedAction = GenericAction[AuthFailedPayload]
+
+type AuthFailedPayload struct {
+	Reason string
+}
+
+// ActionRejectedAction - server refuses action of the client, it is sent to the sender only
+type ActionRejectedAction = GenericAction[ActionRejectedPayload]
+
+type ActionRejectedPayload struct {
+	ActionType ActionType
+	Reason     string
+	Seq        int // sequence number of rejected move command, see MoveStartPayload
+}
+
+// ResyncAction - state of the match sent to player resuming session after reconnect, it replaces state of the client
+type ResyncAction = GenericAction[ResyncPayload]
+
+type ResyncPayload struct {
+	PlayerId PlayerIdType
+	Snapshot
+	Mode        NetworkMode
+	Definitions *Definitions
+	Lobby       Lobby
 }
 
 func UnmarshalAction(bytes []byte) (Action, error) {
@@ -160,6 +649,370 @@ func UnmarshalAction(bytes []byte) (Action, error) {
 		}
 		return action, nil
 
+	case TickActionType:
+		var action TickAction
+		if err := json.Unmarshal(bytes, &action); err != nil {
+			return nil, err
+		}
+		return action, nil
+
+	case StateChecksumActionType:
+		var action StateChecksumAction
+		if err := json.Unmarshal(bytes, &action); err != nil {
+			return nil, err
+		}
+		return action, nil
+
+	case DesyncActionType:
+		var action DesyncAction
+		if err := json.Unmarshal(bytes, &action); err != nil {
+			return nil, err
+		}
+		return action, nil
+
+	case AttackActionType:
+		var action AttackAction
+		if err := json.Unmarshal(bytes, &action); err != nil {
+			return nil, err
+		}
+		return action, nil
+
+	case TargetSetActionType:
+		var action TargetSetAction
+		if err := json.Unmarshal(bytes, &action); err != nil {
+			return nil, err
+		}
+		return action, nil
+
+	case UnitDamagedActionType:
+		var action UnitDamagedAction
+		if err := json.Unmarshal(bytes, &action); err != nil {
+			return nil, err
+		}
+		return action, nil
+
+	case UnitDiedActionType:
+		var action UnitDiedAction
+		if err := json.Unmarshal(bytes, &action); err != nil {
+			return nil, err
+		}
+		return action, nil
+
+	case GatherStartActionType:
+		var action GatherStartAction
+		if err := json.Unmarshal(bytes, &action); err != nil {
+			return nil, err
+		}
+		return action, nil
+
+	case GatherSetActionType:
+		var action GatherSetAction
+		if err := json.Unmarshal(bytes, &action); err != nil {
+			return nil, err
+		}
+		return action, nil
+
+	case ResourceGatheredActionType:
+		var action ResourceGatheredAction
+		if err := json.Unmarshal(bytes, &action); err != nil {
+			return nil, err
+		}
+		return action, nil
+
+	case ResourceDeliveredActionType:
+		var action ResourceDeliveredAction
+		if err := json.Unmarshal(bytes, &action); err != nil {
+			return nil, err
+		}
+		return action, nil
+
+	case PlayerResourcesUpdatedActionType:
+		var action PlayerResourcesUpdatedAction
+		if err := json.Unmarshal(bytes, &action); err != nil {
+			return nil, err
+		}
+		return action, nil
+
+	case PlaceBuildingActionType:
+		var action PlaceBuildingAction
+		if err := json.Unmarshal(bytes, &action); err != nil {
+			return nil, err
+		}
+		return action, nil
+
+	case BuildingPlacedActionType:
+		var action BuildingPlacedAction
+		if err := json.Unmarshal(bytes, &action); err != nil {
+			return nil, err
+		}
+		return action, nil
+
+	case BuildingProgressActionType:
+		var action BuildingProgressAction
+		if err := json.Unmarshal(bytes, &action); err != nil {
+			return nil, err
+		}
+		return action, nil
+
+	case BuildingDamagedActionType:
+		var action BuildingDamagedAction
+		if err := json.Unmarshal(bytes, &action); err != nil {
+			return nil, err
+		}
+		return action, nil
+
+	case BuildingDestroyedActionType:
+		var action BuildingDestroyedAction
+		if err := json.Unmarshal(bytes, &action); err != nil {
+			return nil, err
+		}
+		return action, nil
+
+	case QueueUnitActionType:
+		var action QueueUnitAction
+		if err := json.Unmarshal(bytes, &action); err != nil {
+			return nil, err
+		}
+		return action, nil
+
+	case QueueCancelActionType:
+		var action QueueCancelAction
+		if err := json.Unmarshal(bytes, &action); err != nil {
+			return nil, err
+		}
+		return action, nil
+
+	case UnitQueuedActionType:
+		var action UnitQueuedAction
+		if err := json.Unmarshal(bytes, &action); err != nil {
+			return nil, err
+		}
+		return action, nil
+
+	case UnitDequeuedActionType:
+		var action UnitDequeuedAction
+		if err := json.Unmarshal(bytes, &action); err != nil {
+			return nil, err
+		}
+		return action, nil
+
+	case ProductionProgressActionType:
+		var action ProductionProgressAction
+		if err := json.Unmarshal(bytes, &action); err != nil {
+			return nil, err
+		}
+		return action, nil
+
+	case SetRallyActionType:
+		var action SetRallyAction
+		if err := json.Unmarshal(bytes, &action); err != nil {
+			return nil, err
+		}
+		return action, nil
+
+	case RallySetActionType:
+		var action RallySetAction
+		if err := json.Unmarshal(bytes, &action); err != nil {
+			return nil, err
+		}
+		return action, nil
+
+	case PlayerDefeatedActionType:
+		var action PlayerDefeatedAction
+		if err := json.Unmarshal(bytes, &action); err != nil {
+			return nil, err
+		}
+		return action, nil
+
+	case GameOverActionType:
+		var action GameOverAction
+		if err := json.Unmarshal(bytes, &action); err != nil {
+			return nil, err
+		}
+		return action, nil
+
+	case RoomListActionType:
+		var action RoomListAction
+		if err := json.Unmarshal(bytes, &action); err != nil {
+			return nil, err
+		}
+		return action, nil
+
+	case RoomListSuccessActionType:
+		var action RoomListSuccessAction
+		if err := json.Unmarshal(bytes, &action); err != nil {
+			return nil, err
+		}
+		return action, nil
+
+	case RoomCreateActionType:
+		var action RoomCreateAction
+		if err := json.Unmarshal(bytes, &action); err != nil {
+			return nil, err
+		}
+		return action, nil
+
+	case RoomJoinActionType:
+		var action RoomJoinAction
+		if err := json.Unmarshal(bytes, &action); err != nil {
+			return nil, err
+		}
+		return action, nil
+
+	case RoomJoinedActionType:
+		var action RoomJoinedAction
+		if err := json.Unmarshal(bytes, &action); err != nil {
+			return nil, err
+		}
+		return action, nil
+
+	case RoomJoinFailedActionType:
+		var action RoomJoinFailedAction
+		if err := json.Unmarshal(bytes, &action); err != nil {
+			return nil, err
+		}
+		return action, nil
+
+	case RoomLeaveActionType:
+		var action RoomLeaveAction
+		if err := json.Unmarshal(bytes, &action); err != nil {
+			return nil, err
+		}
+		return action, nil
+
+	case LobbyStateActionType:
+		var action LobbyStateAction
+		if err := json.Unmarshal(bytes, &action); err != nil {
+			return nil, err
+		}
+		return action, nil
+
+	case SelectSlotActionType:
+		var action SelectSlotAction
+		if err := json.Unmarshal(bytes, &action); err != nil {
+			return nil, err
+		}
+		return action, nil
+
+	case SelectColorActionType:
+		var action SelectColorAction
+		if err := json.Unmarshal(bytes, &action); err != nil {
+			return nil, err
+		}
+		return action, nil
+
+	case SelectTeamActionType:
+		var action SelectTeamAction
+		if err := json.Unmarshal(bytes, &action); err != nil {
+			return nil, err
+		}
+		return action, nil
+
+	case ReadyActionType:
+		var action ReadyAction
+		if err := json.Unmarshal(bytes, &action); err != nil {
+			return nil, err
+		}
+		return action, nil
+
+	case JoinRejectedActionType:
+		var action JoinRejectedAction
+		if err := json.Unmarshal(bytes, &action); err != nil {
+			return nil, err
+		}
+		return action, nil
+
+	case SetDiplomacyActionType:
+		var action SetDiplomacyAction
+		if err := json.Unmarshal(bytes, &action); err != nil {
+			return nil, err
+		}
+		return action, nil
+
+	case DiplomacyChangedActionType:
+		var action DiplomacyChangedAction
+		if err := json.Unmarshal(bytes, &action); err != nil {
+			return nil, err
+		}
+		return action, nil
+
+	case UnitEnteredVisionActionType:
+		var action UnitEnteredVisionAction
+		if err := json.Unmarshal(bytes, &action); err != nil {
+			return nil, err
+		}
+		return action, nil
+
+	case UnitLeftVisionActionType:
+		var action UnitLeftVisionAction
+		if err := json.Unmarshal(bytes, &action); err != nil {
+			return nil, err
+		}
+		return action, nil
+
+	case SaveMatchActionType:
+		var action SaveMatchAction
+		if err := json.Unmarshal(bytes, &action); err != nil {
+			return nil, err
+		}
+		return action, nil
+
+	case MatchSavedActionType:
+		var action MatchSavedAction
+		if err := json.Unmarshal(bytes, &action); err != nil {
+			return nil, err
+		}
+		return action, nil
+
+	case MatchSaveFailedActionType:
+		var action MatchSaveFailedAction
+		if err := json.Unmarshal(bytes, &action); err != nil {
+			return nil, err
+		}
+		return action, nil
+
+	case SpectatorJoinActionType:
+		var action SpectatorJoinAction
+		if err := json.Unmarshal(bytes, &action); err != nil {
+			return nil, err
+		}
+		return action, nil
+
+	case AuthActionType:
+		var action AuthAction
+		if err := json.Unmarshal(bytes, &action); err != nil {
+			return nil, err
+		}
+		return action, nil
+
+	case AuthSuccessActionType:
+		var action AuthSuccessAction
+		if err := json.Unmarshal(bytes, &action); err != nil {
+			return nil, err
+		}
+		return action, nil
+
+	case AuthFailedActionType:
+		var action AuthFailedAction
+		if err := json.Unmarshal(bytes, &action); err != nil {
+			return nil, err
+		}
+		return action, nil
+
+	case ActionRejectedActionType:
+		var action ActionRejectedAction
+		if err := json.Unmarshal(bytes, &action); err != nil {
+			return nil, err
+		}
+		return action, nil
+
+	case ResyncActionType:
+		var action ResyncAction
+		if err := json.Unmarshal(bytes, &action); err != nil {
+			return nil, err
+		}
+		return action, nil
+
 	default:
 		return nil, errors.New("action type unrecognized")
 	}
@@ -0,0 +1,230 @@
+package game
+
+import (
+	"bytes"
+	"image"
+	"image/color"
+	"sort"
+
+	"github.com/google/uuid"
+)
+
+const (
+	DropoffAbility = "dropoff"
+
+	progressInterval  = TickRate // ticks between construction and production progress updates
+	defaultQueueLimit = 5
+)
+
+var ZeroBuildingId = BuildingIdType(uuid.Nil)
+
+type BuildingIdType uuid.UUID
+
+type BuildingTypeIdType string
+
+// BuildingType is template of buildings, values are in human friendly units and converted by NewBuildingOfType
+type BuildingType struct {
+	Id         BuildingTypeIdType `json:"id"`
+	Name       string             `json:"name"`
+	Width      int                `json:"width"`     // tiles
+	Height     int                `json:"height"`    // tiles
+	HP         int                `json:"hp"`        // hit points
+	BuildTime  float64            `json:"buildTime"` // seconds
+	Sight      int                `json:"sight"`     // tiles
+	Cost       Resources          `json:"cost"`
+	Produces   []UnitTypeIdType   `json:"produces"`   // unit types which can be queued
+	QueueLimit int                `json:"queueLimit"` // max queued units, default 5
+	Abilities  []string           `json:"abilities"`
+}
+
+// Building is structure occupying all tiles of its footprint
+type Building struct {
+	Id        BuildingIdType
+	Type      BuildingTypeIdType
+	Owner     PlayerIdType
+	Color     color.RGBA
+	Position  image.Point // top left tile
+	Footprint image.Point // size in tiles
+	HP        int
+	MaxHP     int
+	Progress  int // ticks of construction done
+	BuildTime int // ticks of construction needed
+	Sight     int
+	Abilities []string
+	// production
+	Produces   []UnitTypeIdType
+	QueueLimit int
+	Queue      []QueueItem
+	Rally      image.Point // where produced units appear
+}
+
+func NewBuildingOfType(t BuildingType, owner PlayerIdType, c color.RGBA, position image.Point) *Building {
+	buildTime := int(t.BuildTime * TickRate)
+	if buildTime < 1 {
+		buildTime = 1
+	}
+	queueLimit := t.QueueLimit
+	if queueLimit == 0 {
+		queueLimit = defaultQueueLimit
+	}
+	return &Building{
+		Id:         NewBuildingId(),
+		Type:       t.Id,
+		Owner:      owner,
+		Color:      c,
+		Position:   position,
+		Footprint:  image.Pt(t.Width, t.Height),
+		HP:         t.HP,
+		MaxHP:      t.HP,
+		BuildTime:  buildTime,
+		Sight:      t.Sight,
+		Abilities:  t.Abilities,
+		Produces:   t.Produces,
+		QueueLimit: queueLimit,
+		// tile below the middle of the building
+		Rally: position.Add(image.Pt(t.Width/2, t.Height)),
+	}
+}
+
+func NewBuildingId() BuildingIdType {
+	return BuildingIdType(uuid.New())
+}
+
+// Rect returns tiles occupied by the building
+func (b *Building) Rect() image.Rectangle {
+	return image.Rectangle{Min: b.Position, Max: b.Position.Add(b.Footprint)}
+}
+
+func (b *Building) Points() []image.Point {
+	return FootprintPoints(b.Position, b.Footprint)
+}
+
+func FootprintPoints(position, footprint image.Point) []image.Point {
+	points := make([]image.Point, 0, footprint.X*footprint.Y)
+	for x := 0; x < footprint.X; x++ {
+		for y := 0; y < footprint.Y; y++ {
+			points = append(points, position.Add(image.Pt(x, y)))
+		}
+	}
+	return points
+}
+
+// Vision returns tiles seen from the building
+func (b *Building) Vision() []image.Point {
+	center := b.Position.Add(b.Footprint.Div(2))
+	offsets := visionOffsets(b.Sight)
+	points := make([]image.Point, 0, len(offsets))
+	for _, v := range offsets {
+		points = append(points, center.Add(v))
+	}
+	return points
+}
+
+// ClosestPoint returns tile of the building closest to p
+func (b *Building) ClosestPoint(p image.Point) image.Point {
+	r := b.Rect()
+	return image.Pt(clamp(p.X, r.Min.X, r.Max.X-1), clamp(p.Y, r.Min.Y, r.Max.Y-1))
+}
+
+func (b *Building) IsComplete() bool {
+	return b.Progress >= b.BuildTime
+}
+
+func (b *Building) IsAlive() bool {
+	return b.HP > 0
+}
+
+func (b *Building) HasAbility(ability string) bool {
+	for _, a := range b.Abilities {
+		if a == ability {
+			return true
+		}
+	}
+	return false
+}
+
+// updateConstruction advances construction of incomplete building
+func (g *GameLogic) updateConstruction(b *Building, dispatch DispatchFunc) {
+	if !b.IsAlive() || b.IsComplete() {
+		return
+	}
+	b.Progress++
+	if b.Progress%progressInterval == 0 || b.IsComplete() {
+		dispatch(BuildingProgressAction{
+			Type: BuildingProgressActionType,
+			Payload: BuildingProgressPayload{
+				BuildingId: b.Id,
+				Progress:   b.Progress,
+			},
+		})
+	}
+}
+
+func (g *GameLogic) handleBuildingPlacedAction(action BuildingPlacedAction) {
+	b := action.Payload
+	g.store.StoreBuilding(&b)
+	g.placeBuilding(&b)
+}
+
+func (g *GameLogic) handleBuildingProgressAction(action BuildingProgressAction) {
+	b := g.store.GetBuildingById(action.Payload.BuildingId)
+	if b == nil {
+		return
+	}
+	b.Progress = action.Payload.Progress
+}
+
+func (g *GameLogic) handleBuildingDamagedAction(action BuildingDamagedAction) {
+	b := g.store.GetBuildingById(action.Payload.BuildingId)
+	if b == nil {
+		return
+	}
+	b.HP = action.Payload.HP
+}
+
+func (g *GameLogic) handleBuildingDestroyedAction(action BuildingDestroyedAction) {
+	id := action.Payload.BuildingId
+	b := g.store.GetBuildingById(id)
+	if b == nil {
+		return
+	}
+	b.HP = 0
+	for _, p := range b.Points() {
+		if t, ok := g.store.GetTile(p); ok && t.Building == b {
+			t.Building = nil
+		}
+	}
+	g.store.RemoveBuilding(id)
+	for _, u := range g.store.GetAllUnits() {
+		if u.TargetBuilding == id {
+			u.TargetBuilding = ZeroBuildingId
+		}
+	}
+}
+
+func (g *GameLogic) placeBuilding(b *Building) {
+	for _, p := range b.Points() {
+		t, ok := g.store.GetTile(p)
+		if !ok {
+			t = g.store.CreateTile(p)
+		}
+		t.Building = b
+	}
+}
+
+func sortedBuildings(buildings []*Building) []*Building {
+	sort.Slice(buildings, func(i, j int) bool {
+		return bytes.Compare(buildings[i].Id[:], buildings[j].Id[:]) < 0
+	})
+	return buildings
+}
+
+func clamp(v, min, max int) int {
+	if v < min {
+		return min
+	}
+	if v > max {
+		return max
+	}
+	return v
+}
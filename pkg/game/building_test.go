@@ -0,0 +1,47 @@
+package game
+
+import (
+	"image"
+	"testing"
+)
+
+func TestUnitStopsBeforeBuildingPlacedOnPath(t *testing.T) {
+	g := NewGameLogic(NewStoreImpl())
+	building := Building{
+		Id:        BuildingIdType{1},
+		Owner:     testPlayer2,
+		Position:  image.Pt(3, 0),
+		Footprint: image.Pt(2, 2),
+		HP:        100,
+		MaxHP:     100,
+	}
+	stream := tickStream(200, map[int][]Action{
+		0: {spawn(testUnit(testUnit1, testPlayer1, image.Pt(0, 0)))},
+		1: {MoveStepAction{
+			Type: MoveStepActionType,
+			Payload: MoveStepPayload{
+				UnitId:   testUnit1,
+				Position: ToPF(image.Pt(0, 0)),
+				Path:     []image.Point{image.Pt(1, 0), image.Pt(2, 0), image.Pt(3, 0), image.Pt(4, 0), image.Pt(5, 0)},
+				Costs:    []int{plainCost, plainCost, plainCost, plainCost, plainCost},
+			},
+		}},
+		2: {BuildingPlacedAction{Type: BuildingPlacedActionType, Payload: building}},
+	})
+	for _, action := range stream {
+		handleTick(g, action)
+	}
+
+	u := g.store.GetUnitById(testUnit1)
+	if u.IsMoving() {
+		t.Fatalf("unit still moving along %v", u.Path)
+	}
+	if p := u.Position.ImagePoint(); p != image.Pt(2, 0) {
+		t.Errorf("unit stopped at %v, want %v", p, image.Pt(2, 0))
+	}
+	for _, p := range building.Points() {
+		if tile, ok := g.store.GetTile(p); ok && tile.Unit != nil {
+			t.Errorf("unit placed on building tile %v", p)
+		}
+	}
+}
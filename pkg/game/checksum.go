@@ -0,0 +1,48 @@
+package game
+
+import (
+	"bytes"
+	"encoding/binary"
+	"hash/fnv"
+	"sort"
+)
+
+// ChecksumInterval is number of ticks between state checksums in lockstep mode
+const ChecksumInterval = TickRate
+
+// Checksum returns hash of simulation state, equal on every game logic that is in sync
+func (g *GameLogic) Checksum() uint64 {
+	h := fnv.New64a()
+	for _, u := range sortedUnits(g.store.GetAllUnits()) {
+		h.Write(u.Id[:])
+		binary.Write(h, binary.LittleEndian, []int64{
+			int64(u.Position.X),
+			int64(u.Position.Y),
+			int64(u.Step),
+			int64(len(u.Path)),
+			int64(u.HP),
+			int64(u.Reload),
+			int64(u.Carry),
+			int64(u.GatherProgress),
+		})
+	}
+	for _, b := range sortedBuildings(g.store.GetAllBuildings()) {
+		h.Write(b.Id[:])
+		binary.Write(h, binary.LittleEndian, []int64{
+			int64(b.HP),
+			int64(b.Progress),
+			int64(len(b.Queue)),
+		})
+		if len(b.Queue) > 0 {
+			binary.Write(h, binary.LittleEndian, int64(b.Queue[0].Progress))
+		}
+	}
+	return h.Sum64()
+}
+
+func sortedUnits(units []*Unit) []*Unit {
+	sort.Slice(units, func(i, j int) bool {
+		return bytes.Compare(units[i].Id[:], units[j].Id[:]) < 0
+	})
+	return units
+}
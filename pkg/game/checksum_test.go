@@ -0,0 +1,154 @@
+package game
+
+import (
+	"image"
+	"testing"
+
+	"github.com/bmcszk/gptrts/pkg/world"
+)
+
+var (
+	testPlayer1 = PlayerIdType{1}
+	testPlayer2 = PlayerIdType{2}
+	testUnit1   = UnitIdType{1}
+	testUnit2   = UnitIdType{2}
+)
+
+func testUnit(id UnitIdType, owner PlayerIdType, p image.Point) Unit {
+	return Unit{
+		Id:       id,
+		Owner:    owner,
+		Position: ToPF(p),
+		Size:     image.Pt(1, 1),
+		Speed:    FixedOne / 8,
+		HP:       30,
+		MaxHP:    30,
+		Damage:   7,
+		Range:    2 * FixedOne,
+		Cooldown: 5,
+	}
+}
+
+func spawn(u Unit) Action {
+	return SpawnUnitAction{Type: SpawnUnitActionType, Payload: u}
+}
+
+// tickStream returns bundles with actions at given ticks, other ticks are empty
+func tickStream(ticks int, actions map[int][]Action) []TickAction {
+	stream := make([]TickAction, 0, ticks)
+	for i := 0; i < ticks; i++ {
+		stream = append(stream, TickAction{
+			Type:    TickActionType,
+			Payload: TickPayload{Tick: i, Actions: actions[i]},
+		})
+	}
+	return stream
+}
+
+func TestChecksumDeterminism(t *testing.T) {
+	tests := []struct {
+		name    string
+		stream  func() []TickAction // called for each game logic, so they share no state
+		loaded  []world.Tile        // tiles loaded by the first game logic only, e.g. area its player looked at
+		changes bool                // state at the end differs from the first tick
+	}{
+		{
+			name: "idle units",
+			stream: func() []TickAction {
+				return tickStream(30, map[int][]Action{
+					0: {spawn(testUnit(testUnit1, testPlayer1, image.Pt(0, 0))), spawn(testUnit(testUnit2, testPlayer2, image.Pt(10, 10)))},
+				})
+			},
+		},
+		{
+			name: "moving unit",
+			stream: func() []TickAction {
+				return tickStream(120, map[int][]Action{
+					0: {spawn(testUnit(testUnit1, testPlayer1, image.Pt(0, 0)))},
+					1: {MoveStepAction{
+						Type: MoveStepActionType,
+						Payload: MoveStepPayload{
+							UnitId:   testUnit1,
+							Position: ToPF(image.Pt(0, 0)),
+							Path:     []image.Point{image.Pt(1, 0), image.Pt(2, 1), image.Pt(3, 1)},
+							Costs:    []int{plainCost, plainCost, 2 * plainCost},
+						},
+					}},
+				})
+			},
+			changes: true,
+		},
+		{
+			name: "combat",
+			stream: func() []TickAction {
+				return tickStream(60, map[int][]Action{
+					0: {spawn(testUnit(testUnit1, testPlayer1, image.Pt(0, 0))), spawn(testUnit(testUnit2, testPlayer2, image.Pt(1, 1)))},
+					1: {
+						TargetSetAction{Type: TargetSetActionType, Payload: TargetPayload{UnitId: testUnit1, TargetId: testUnit2}},
+						TargetSetAction{Type: TargetSetActionType, Payload: TargetPayload{UnitId: testUnit2, TargetId: testUnit1}},
+					},
+				})
+			},
+			changes: true,
+		},
+		{
+			name: "gathering node loaded by one game logic only",
+			stream: func() []TickAction {
+				// node is smaller than capacity, drop-off is far so worker keeps the load
+				node := ResourceNode{Point: image.Pt(2, 0), Type: Wood, Amount: 5}
+				return tickStream(200, map[int][]Action{
+					0: {spawn(testUnit(testUnit1, testPlayer1, image.Pt(1, 0)))},
+					1: {GatherSetAction{
+						Type: GatherSetActionType,
+						Payload: GatherSetPayload{
+							UnitId:  testUnit1,
+							Point:   &node.Point,
+							Node:    &node,
+							Dropoff: image.Pt(10, 10),
+						},
+					}},
+				})
+			},
+			loaded: []world.Tile{
+				{Point: image.Pt(1, 0), LandType: "grass"},
+				{Point: image.Pt(2, 0), LandType: "forest", FrontStyleClass: "forest1"},
+			},
+			changes: true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			g1, g2 := NewGameLogic(NewStoreImpl()), NewGameLogic(NewStoreImpl())
+			if tt.loaded != nil {
+				g1.HandleAction(MapLoadSuccessAction{
+					Type:    MapLoadSuccessActionType,
+					Payload: MapLoadSuccessPayload{WorldResponse: world.WorldResponse{Tiles: tt.loaded}},
+				}, func(Action) {})
+			}
+			s1, s2 := tt.stream(), tt.stream()
+			var first uint64
+			for i := range s1 {
+				handleTick(g1, s1[i])
+				handleTick(g2, s2[i])
+				c1, c2 := g1.Checksum(), g2.Checksum()
+				if c1 != c2 {
+					t.Fatalf("tick %d: checksums differ %x != %x", i, c1, c2)
+				}
+				if i == 0 {
+					first = c1
+				}
+			}
+			if changed := g1.Checksum() != first; changed != tt.changes {
+				t.Errorf("state changed = %v, want %v", changed, tt.changes)
+			}
+		})
+	}
+}
+
+func handleTick(g *GameLogic, action TickAction) {
+	var dispatch DispatchFunc
+	dispatch = func(a Action) {
+		g.HandleAction(a, dispatch)
+	}
+	g.HandleAction(action, dispatch)
+}
@@ -0,0 +1,125 @@
+package game
+
+// updateCombat attacks target of the unit when it is in range and weapon is reloaded
+func (g *GameLogic) updateCombat(u *Unit, dispatch DispatchFunc) {
+	if !u.IsAlive() {
+		return
+	}
+	if u.Reload > 0 {
+		u.Reload--
+	}
+	if u.Reload > 0 {
+		return
+	}
+	if u.Target != ZeroUnitId {
+		g.attackUnit(u, dispatch)
+	} else if u.TargetBuilding != ZeroBuildingId {
+		g.attackBuilding(u, dispatch)
+	}
+}
+
+func (g *GameLogic) attackUnit(u *Unit, dispatch DispatchFunc) {
+	target := g.store.GetUnitById(u.Target)
+	if target == nil || !target.IsAlive() || !CanAttack(g.store, u.Owner, target.Owner) {
+		u.Target = ZeroUnitId
+		return
+	}
+	if !u.InRange(target) {
+		return
+	}
+
+	u.Reload = u.Cooldown
+	hp := target.HP - u.Damage
+	dispatch(UnitDamagedAction{
+		Type: UnitDamagedActionType,
+		Payload: UnitDamagedPayload{
+			UnitId:     target.Id,
+			AttackerId: u.Id,
+			Damage:     u.Damage,
+			HP:         hp,
+		},
+	})
+	if hp <= 0 {
+		dispatch(UnitDiedAction{
+			Type: UnitDiedActionType,
+			Payload: UnitDiedPayload{
+				UnitId:   target.Id,
+				KillerId: u.Id,
+			},
+		})
+	}
+}
+
+func (g *GameLogic) attackBuilding(u *Unit, dispatch DispatchFunc) {
+	target := g.store.GetBuildingById(u.TargetBuilding)
+	if target == nil || !target.IsAlive() || !CanAttack(g.store, u.Owner, target.Owner) {
+		u.TargetBuilding = ZeroBuildingId
+		return
+	}
+	if !u.InRangeOfBuilding(target) {
+		return
+	}
+
+	u.Reload = u.Cooldown
+	hp := target.HP - u.Damage
+	dispatch(BuildingDamagedAction{
+		Type: BuildingDamagedActionType,
+		Payload: BuildingDamagedPayload{
+			BuildingId: target.Id,
+			AttackerId: u.Id,
+			Damage:     u.Damage,
+			HP:         hp,
+		},
+	})
+	if hp <= 0 {
+		dispatch(BuildingDestroyedAction{
+			Type: BuildingDestroyedActionType,
+			Payload: BuildingDestroyedPayload{
+				BuildingId: target.Id,
+				KillerId:   u.Id,
+			},
+		})
+	}
+}
+
+func (u *Unit) InRange(target *Unit) bool {
+	return u.Position.Dist(target.Position) <= u.Range
+}
+
+func (u *Unit) InRangeOfBuilding(target *Building) bool {
+	closest := ToPF(target.ClosestPoint(u.Position.ImagePoint()))
+	return u.Position.Dist(closest) <= u.Range
+}
+
+func (g *GameLogic) handleTargetSetAction(action TargetSetAction) {
+	unit := g.store.GetUnitById(action.Payload.UnitId)
+	if unit == nil {
+		return
+	}
+	unit.Target = action.Payload.TargetId
+	unit.TargetBuilding = action.Payload.BuildingId
+}
+
+func (g *GameLogic) handleUnitDamagedAction(action UnitDamagedAction) {
+	unit := g.store.GetUnitById(action.Payload.UnitId)
+	if unit == nil {
+		return
+	}
+	unit.HP = action.Payload.HP
+}
+
+func (g *GameLogic) handleUnitDiedAction(action UnitDiedAction) {
+	id := action.Payload.UnitId
+	if unit := g.store.GetUnitById(id); unit != nil {
+		unit.HP = 0
+	}
+	for _, tile := range g.store.GetTilesByUnitId(id) {
+		tile.Unit = nil
+	}
+	g.store.RemoveUnit(id)
+	for _, u := range g.store.GetAllUnits() {
+		if u.Target == id {
+			u.Target = ZeroUnitId
+		}
+	}
+}
@@ -0,0 +1,90 @@
+package game
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+)
+
+// Definitions are game rules data loaded from definitions file
+type Definitions struct {
+	Units             []UnitType       `json:"units"`
+	Buildings         []BuildingType   `json:"buildings"`
+	StartingArmy      []UnitTypeIdType `json:"startingArmy"` // units spawned for every new player
+	StartingResources Resources        `json:"startingResources"`
+	Victory           Victory          `json:"victory"`
+	unitTypes         map[UnitTypeIdType]UnitType
+	buildingTypes     map[BuildingTypeIdType]BuildingType
+}
+
+func LoadDefinitions(path string) (*Definitions, error) {
+	bytes, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	var defs Definitions
+	if err := json.Unmarshal(bytes, &defs); err != nil {
+		return nil, fmt.Errorf("definitions %s: %w", path, err)
+	}
+	if err := defs.Init(); err != nil {
+		return nil, fmt.Errorf("definitions %s: %w", path, err)
+	}
+	return &defs, nil
+}
+
+// Init indexes types by id, needed after definitions are decoded
+func (d *Definitions) Init() error {
+	d.unitTypes = make(map[UnitTypeIdType]UnitType, len(d.Units))
+	for _, t := range d.Units {
+		if _, ok := d.unitTypes[t.Id]; ok {
+			return fmt.Errorf("unit type %s defined twice", t.Id)
+		}
+		d.unitTypes[t.Id] = t
+	}
+	d.buildingTypes = make(map[BuildingTypeIdType]BuildingType, len(d.Buildings))
+	for _, t := range d.Buildings {
+		if _, ok := d.buildingTypes[t.Id]; ok {
+			return fmt.Errorf("building type %s defined twice", t.Id)
+		}
+		if t.Width < 1 || t.Height < 1 {
+			return fmt.Errorf("building type %s has empty footprint", t.Id)
+		}
+		for _, id := range t.Produces {
+			if _, ok := d.unitTypes[id]; !ok {
+				return fmt.Errorf("building type %s produces unit type %s not defined", t.Id, id)
+			}
+		}
+		d.buildingTypes[t.Id] = t
+	}
+	for _, c := range d.Victory.Conditions {
+		switch c {
+		case AnnihilationCondition, BuildingCondition, ScoreCondition:
+		default:
+			return fmt.Errorf("victory condition %s unknown", c)
+		}
+	}
+	for _, id := range d.Victory.Buildings {
+		if _, ok := d.buildingTypes[id]; !ok {
+			return fmt.Errorf("key building type %s not defined", id)
+		}
+	}
+	if d.Victory.Has(ScoreCondition) && d.Victory.TimeLimit <= 0 {
+		return fmt.Errorf("score condition needs time limit")
+	}
+	for _, id := range d.StartingArmy {
+		if _, ok := d.unitTypes[id]; !ok {
+			return fmt.Errorf("starting army unit type %s not defined", id)
+		}
+	}
+	return nil
+}
+
+func (d *Definitions) UnitType(id UnitTypeIdType) (UnitType, bool) {
+	t, ok := d.unitTypes[id]
+	return t, ok
+}
+
+func (d *Definitions) BuildingType(id BuildingTypeIdType) (BuildingType, bool) {
+	t, ok := d.buildingTypes[id]
+	return t, ok
+}
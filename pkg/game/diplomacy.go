@@ -0,0 +1,92 @@
+package game
+
+type Stance string
+
+const (
+	AllyStance    Stance = "ally"    // shares vision, cannot be attacked
+	NeutralStance Stance = "neutral" // cannot be attacked
+	EnemyStance   Stance = "enemy"
+)
+
+// Relation is stance of player towards other player, it overrides stance given by teams
+type Relation struct {
+	PlayerId PlayerIdType
+	Stance   Stance
+}
+
+// StanceTowards returns stance of player towards other player, players of the same team are allies by default
+func (p *Player) StanceTowards(other *Player) Stance {
+	if p.Id == other.Id {
+		return AllyStance
+	}
+	for _, r := range p.Relations {
+		if r.PlayerId == other.Id {
+			return r.Stance
+		}
+	}
+	if p.Team != 0 && p.Team == other.Team {
+		return AllyStance
+	}
+	return EnemyStance
+}
+
+func (p *Player) setStance(playerId PlayerIdType, stance Stance) {
+	for i, r := range p.Relations {
+		if r.PlayerId == playerId {
+			p.Relations[i].Stance = stance
+			return
+		}
+	}
+	p.Relations = append(p.Relations, Relation{PlayerId: playerId, Stance: stance})
+}
+
+// StanceOf returns stance of player towards other player, unknown players are enemies
+func StanceOf(store Store, from, to PlayerIdType) Stance {
+	if from == to {
+		return AllyStance
+	}
+	p, ok := store.GetPlayer(from)
+	if !ok {
+		return EnemyStance
+	}
+	other, ok := store.GetPlayer(to)
+	if !ok {
+		return EnemyStance
+	}
+	return p.StanceTowards(other)
+}
+
+// CanAttack tells whether units of attacker may damage units and buildings of target player
+func CanAttack(store Store, attacker, target PlayerIdType) bool {
+	return StanceOf(store, attacker, target) == EnemyStance
+}
+
+// SharesVision tells whether viewer sees what units and buildings of owner see
+func SharesVision(store Store, owner, viewer PlayerIdType) bool {
+	return StanceOf(store, owner, viewer) == AllyStance
+}
+
+// Allied tells whether both players consider each other allies
+func Allied(store Store, p1, p2 PlayerIdType) bool {
+	return StanceOf(store, p1, p2) == AllyStance && StanceOf(store, p2, p1) == AllyStance
+}
+
+// handleDiplomacyChangedAction updates stance, units stop attacking players who are not enemies anymore
+func (g *GameLogic) handleDiplomacyChangedAction(action DiplomacyChangedAction) {
+	p, ok := g.store.GetPlayer(action.Payload.PlayerId)
+	if !ok {
+		return
+	}
+	p.setStance(action.Payload.TargetId, action.Payload.Stance)
+	if action.Payload.Stance == EnemyStance {
+		return
+	}
+	for _, u := range g.store.GetUnitsByPlayerId(p.Id) {
+		if target := g.store.GetUnitById(u.Target); target != nil && target.Owner == action.Payload.TargetId {
+			u.Target = ZeroUnitId
+		}
+		if target := g.store.GetBuildingById(u.TargetBuilding); target != nil && target.Owner == action.Payload.TargetId {
+			u.TargetBuilding = ZeroBuildingId
+		}
+	}
+}
@@ -0,0 +1,79 @@
+package game
+
+import "image"
+
+// Visibility of tile for player
+type Visibility int
+
+const (
+	Unexplored Visibility = iota // never seen, terrain is unknown
+	Explored                     // seen before, only terrain and ghosts of buildings are shown
+	Visible                      // seen now
+)
+
+// Fog tracks visibility of tiles for one player and remembers buildings of other players as last seen
+type Fog struct {
+	visible  map[image.Point]bool
+	explored map[image.Point]bool
+	ghosts   map[BuildingIdType]Building // last seen state of buildings of other players
+}
+
+func NewFog() *Fog {
+	return &Fog{
+		visible:  make(map[image.Point]bool),
+		explored: make(map[image.Point]bool),
+		ghosts:   make(map[BuildingIdType]Building),
+	}
+}
+
+// Of returns visibility of tile
+func (f *Fog) Of(p image.Point) Visibility {
+	switch {
+	case f.visible[p]:
+		return Visible
+	case f.explored[p]:
+		return Explored
+	default:
+		return Unexplored
+	}
+}
+
+// Update recomputes visibility of player, snapshots of visible buildings are refreshed,
+// ghosts of buildings which are seen gone are forgotten
+func (f *Fog) Update(store Store, playerId PlayerIdType) {
+	f.visible = VisibleTiles(store, playerId)
+	for p := range f.visible {
+		f.explored[p] = true
+	}
+	for id, ghost := range f.ghosts {
+		if f.seesAny(ghost.Points()) && store.GetBuildingById(id) == nil {
+			delete(f.ghosts, id)
+		}
+	}
+	for _, b := range store.GetAllBuildings() {
+		if SharesVision(store, b.Owner, playerId) || !f.seesAny(b.Points()) {
+			continue
+		}
+		f.ghosts[b.Id] = *b
+	}
+}
+
+// Ghosts returns last seen state of buildings of other players which are not visible now
+func (f *Fog) Ghosts() []Building {
+	ghosts := make([]Building, 0)
+	for _, ghost := range f.ghosts {
+		if !f.seesAny(ghost.Points()) {
+			ghosts = append(ghosts, ghost)
+		}
+	}
+	return ghosts
+}
+
+func (f *Fog) seesAny(points []image.Point) bool {
+	for _, p := range points {
+		if f.visible[p] {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,125 @@
+package game
+
+import (
+	"image"
+	"image/color"
+)
+
+type LobbyPhase string
+
+const (
+	LobbyPhaseWaiting   LobbyPhase = "waiting"   // players choose slots and get ready
+	LobbyPhaseCountdown LobbyPhase = "countdown" // everybody is ready, match starts soon
+	LobbyPhasePlaying   LobbyPhase = "playing"
+)
+
+// PlayerColors are colors offered in lobby
+var PlayerColors = []color.RGBA{
+	{255, 0, 0, 255},
+	{0, 0, 255, 255},
+	{0, 255, 0, 255},
+	{255, 255, 0, 255},
+	{0, 255, 255, 255},
+	{255, 0, 255, 255},
+	{255, 128, 0, 255},
+	{255, 255, 255, 255},
+}
+
+// Slot is place for one player on the map
+type Slot struct {
+	Start    image.Point  // starting point of the player
+	PlayerId PlayerIdType // zero when slot is free
+	Name     string
+	Color    color.RGBA
+	Team     int
+	Ready    bool
+}
+
+func (s Slot) IsFree() bool {
+	return s.PlayerId == ZeroPlayerId
+}
+
+// Lobby is assignment of players to slots before the match starts
+type Lobby struct {
+	Phase     LobbyPhase
+	Slots     []Slot
+	Countdown int // ticks left to start of the match
+}
+
+func NewLobby(starts []image.Point) Lobby {
+	slots := make([]Slot, 0, len(starts))
+	for i, p := range starts {
+		slots = append(slots, Slot{Start: p, Team: i + 1})
+	}
+	return Lobby{
+		Phase: LobbyPhaseWaiting,
+		Slots: slots,
+	}
+}
+
+// SlotOf returns index of slot taken by player or -1
+func (l *Lobby) SlotOf(playerId PlayerIdType) int {
+	for i, s := range l.Slots {
+		if !s.IsFree() && s.PlayerId == playerId {
+			return i
+		}
+	}
+	return -1
+}
+
+// FreeSlot returns index of first free slot or -1
+func (l *Lobby) FreeSlot() int {
+	for i, s := range l.Slots {
+		if s.IsFree() {
+			return i
+		}
+	}
+	return -1
+}
+
+// ColorTaken tells whether other player than playerId uses the color
+func (l *Lobby) ColorTaken(c color.RGBA, playerId PlayerIdType) bool {
+	for _, s := range l.Slots {
+		if !s.IsFree() && s.PlayerId != playerId && s.Color == c {
+			return true
+		}
+	}
+	return false
+}
+
+// AllReady tells whether there is at least one player and all players are ready
+func (l *Lobby) AllReady() bool {
+	players := 0
+	for _, s := range l.Slots {
+		if s.IsFree() {
+			continue
+		}
+		if !s.Ready {
+			return false
+		}
+		players++
+	}
+	return players > 0
+}
+
+func (l *Lobby) IsPlaying() bool {
+	return l.Phase == LobbyPhasePlaying
+}
+
+// handleLobbyStateAction updates players from their slots
+func (g *GameLogic) handleLobbyStateAction(action LobbyStateAction) {
+	for _, s := range action.Payload.Slots {
+		if s.IsFree() {
+			continue
+		}
+		player := Player{Id: s.PlayerId}
+		if p, ok := g.store.GetPlayer(s.PlayerId); ok {
+			player = *p
+		}
+		player.Name = s.Name
+		player.Color = s.Color
+		player.Team = s.Team
+		player.Start = ToPF(s.Start)
+		g.store.StorePlayer(player)
+	}
+}
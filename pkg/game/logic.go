@@ -13,7 +13,9 @@ type ActionsHandler interface {
 }
 
 type GameLogic struct {
-	store Store
+	store  Store
+	tick   int
+	result *GameOverPayload
 }
 
 func NewGameLogic(store Store) *GameLogic {
@@ -28,29 +30,108 @@ func (g *GameLogic) HandleAction(action Action, dispatch DispatchFunc) {
 		g.handlePlayerJoinSuccessAction(a, dispatch)
 	case SpawnUnitAction:
 		g.handleSpawnUnitAction(a, dispatch)
-	case MoveStartAction:
-		g.handleMoveStartAction(a)
 	case MoveStepAction:
 		g.handleMoveStepAction(a, dispatch)
 	case MoveStopAction:
 		g.handleMoveStopAction(a)
 	case MapLoadSuccessAction:
 		g.handleMapLoadSuccessAction(a)
+	case TickAction:
+		g.handleTickAction(a, dispatch)
+	case TargetSetAction:
+		g.handleTargetSetAction(a)
+	case UnitDamagedAction:
+		g.handleUnitDamagedAction(a)
+	case UnitDiedAction:
+		g.handleUnitDiedAction(a)
+	case GatherSetAction:
+		g.handleGatherSetAction(a)
+	case ResourceGatheredAction:
+		g.handleResourceGatheredAction(a)
+	case ResourceDeliveredAction:
+		g.handleResourceDeliveredAction(a)
+	case PlayerResourcesUpdatedAction:
+		g.handlePlayerResourcesUpdatedAction(a)
+	case BuildingPlacedAction:
+		g.handleBuildingPlacedAction(a)
+	case BuildingProgressAction:
+		g.handleBuildingProgressAction(a)
+	case BuildingDamagedAction:
+		g.handleBuildingDamagedAction(a)
+	case BuildingDestroyedAction:
+		g.handleBuildingDestroyedAction(a)
+	case UnitQueuedAction:
+		g.handleUnitQueuedAction(a)
+	case UnitDequeuedAction:
+		g.handleUnitDequeuedAction(a)
+	case ProductionProgressAction:
+		g.handleProductionProgressAction(a)
+	case RallySetAction:
+		g.handleRallySetAction(a)
+	case PlayerDefeatedAction:
+		g.handlePlayerDefeatedAction(a)
+	case GameOverAction:
+		g.handleGameOverAction(a)
+	case LobbyStateAction:
+		g.handleLobbyStateAction(a)
+	case DiplomacyChangedAction:
+		g.handleDiplomacyChangedAction(a)
+	case UnitEnteredVisionAction:
+		g.handleUnitEnteredVisionAction(a)
+	case UnitLeftVisionAction:
+		g.handleUnitLeftVisionAction(a)
 	}
 }
 
+// Tick returns number of the next simulation tick
+func (g *GameLogic) Tick() int {
+	return g.tick
+}
+
+// Update advances simulation by one tick, units are updated in id order to stay deterministic.
+// Simulation stops when the game is over.
+func (g *GameLogic) Update(dispatch DispatchFunc) {
+	if g.result != nil {
+		return
+	}
+	units := sortedUnits(g.store.GetAllUnits())
+	for _, u := range units {
+		u.Update(dispatch)
+	}
+	for _, u := range units {
+		g.updateCombat(u, dispatch)
+		g.updateGathering(u, dispatch)
+	}
+	for _, b := range sortedBuildings(g.store.GetAllBuildings()) {
+		g.updateConstruction(b, dispatch)
+		g.updateProduction(b, dispatch)
+	}
+	g.tick++
+}
+
 func (g *GameLogic) handlePlayerJoinSuccessAction(action PlayerJoinSuccessAction, dispatch DispatchFunc) {
+	g.tick = action.Payload.Tick
+	g.result = action.Payload.Result
 	for _, u := range action.Payload.Units {
-		unit := &u
-		g.store.StoreUnit(unit)
-		if err := g.placeUnit(unit); err != nil {
+		unit := u
+		g.store.StoreUnit(&unit)
+		if err := g.placeUnit(&unit); err != nil {
 			log.Println(err)
 		}
+		g.updateSight(&unit)
+	}
+	for _, b := range action.Payload.Buildings {
+		building := b
+		g.store.StoreBuilding(&building)
+		g.placeBuilding(&building)
 	}
 	for _, p := range action.Payload.Players {
 		player := p
 		g.store.StorePlayer(player)
 	}
+	for _, stockpile := range action.Payload.Stockpiles {
+		g.store.StoreResources(stockpile.PlayerId, stockpile.Resources)
+	}
 }
 
 func (g *GameLogic) handleSpawnUnitAction(action SpawnUnitAction, dispatch DispatchFunc) {
@@ -60,33 +141,34 @@ func (g *GameLogic) handleSpawnUnitAction(action SpawnUnitAction, dispatch Dispa
 		log.Println(err)
 		//dispatch error action
 	}
-}
-
-func (g *GameLogic) handleMoveStartAction(action MoveStartAction) {
-	unit := g.store.GetUnitById(action.Payload.UnitId)
-
-	unit.MoveTo(action.Payload.Point)
+	g.updateSight(unit)
 }
 
 func (g *GameLogic) handleMoveStepAction(action MoveStepAction, dispatch DispatchFunc) {
+	unit := g.store.GetUnitById(action.Payload.UnitId)
+	if unit == nil {
+		return
+	}
 	//clean position
 	for _, tile := range g.store.GetTilesByUnitId(action.Payload.UnitId) {
 		tile.Unit = nil
 	}
-	unit := g.store.GetUnitById(action.Payload.UnitId)
 
 	unit.Position = action.Payload.Position
 	unit.Path = action.Payload.Path
+	unit.Costs = action.Payload.Costs
 	unit.Step = action.Payload.Step
+	unit.Seq = action.Payload.Seq
+	g.updateSight(unit)
 
 	if err := g.placeUnit(unit); err != nil {
 		log.Println(err)
 		//dispatch error action
 	}
-	//reserve next step
+	//reserve next step, building may have been placed on the path after it was planned
 	if len(action.Payload.Path) > action.Payload.Step {
 		nextStep := action.Payload.Path[action.Payload.Step]
-		if err := g.placeUnit(unit, nextStep); err != nil {
+		if err := g.enterTile(unit, nextStep); err != nil {
 			dispatch(MoveStopAction{
 				Type:    MoveStopActionType,
 				Payload: unit.Id,
@@ -106,15 +188,51 @@ func (g *GameLogic) handleMoveStepAction(action MoveStepAction, dispatch Dispatc
 
 func (g *GameLogic) handleMoveStopAction(action MoveStopAction) {
 	unit := g.store.GetUnitById(action.Payload)
+	if unit == nil {
+		return
+	}
 
 	unit.Path = []image.Point{}
+	unit.Costs = nil
 	unit.Step = 0
 }
 
 func (g *GameLogic) handleMapLoadSuccessAction(action MapLoadSuccessAction) {
+	tiles := make([]*Tile, 0, len(action.Payload.Tiles))
 	for _, t := range action.Payload.Tiles {
-		g.store.StoreTile(t)
+		tiles = append(tiles, g.store.StoreTile(t))
+	}
+	for _, node := range action.Payload.ResourceNodes {
+		g.initResourceNode(node)
+	}
+	for _, t := range tiles {
+		placeResourceNode(t)
+	}
+	// terrain may hide or reveal what units see
+	for _, u := range g.store.GetAllUnits() {
+		g.updateSight(u)
+	}
+}
+
+// handleTickAction applies commands of the tick bundle and advances simulation, used in lockstep mode
+func (g *GameLogic) handleTickAction(action TickAction, dispatch DispatchFunc) {
+	if action.Payload.Tick < g.tick {
+		// already part of state snapshot
+		return
+	}
+	g.tick = action.Payload.Tick
+	for _, a := range action.Payload.Actions {
+		g.HandleAction(a, dispatch)
+	}
+	g.Update(dispatch)
+}
+
+// enterTile reserves tile for the next step of the unit, tiles of buildings cannot be entered
+func (g *GameLogic) enterTile(unit *Unit, p image.Point) error {
+	if t, ok := g.store.GetTile(p); ok && t.Building != nil {
+		return errors.New("building")
 	}
+	return g.placeUnit(unit, p)
 }
 
 func (g *GameLogic) placeUnit(unit *Unit, positions ...image.Point) error {
@@ -0,0 +1,112 @@
+package game
+
+import (
+	"image"
+	"sort"
+	"sync"
+)
+
+var (
+	// HighGroundSight is extra sight in tiles per ground level the unit stands on
+	HighGroundSight = 1
+	// MaxHighGroundSight limits extra sight given by high ground
+	MaxHighGroundSight = 3
+)
+
+// losRay is tile in sight radius and the previous tile on the line from the observer to it
+type losRay struct {
+	offset image.Point
+	parent image.Point
+}
+
+var (
+	losCache = map[int][]losRay{}
+	losMux   = &sync.Mutex{} // rooms compute sight concurrently
+)
+
+// losRays returns tiles in sight radius relative to observer, every tile follows its parent
+func losRays(sight int) []losRay {
+	losMux.Lock()
+	defer losMux.Unlock()
+	if rays, ok := losCache[sight]; ok {
+		return rays
+	}
+	offsets := visionOffsets(sight)
+	rays := make([]losRay, 0, len(offsets))
+	for _, p := range offsets {
+		rays = append(rays, losRay{offset: p, parent: lineParent(p)})
+	}
+	sort.SliceStable(rays, func(i, j int) bool {
+		return chebyshev(rays[i].offset, ZeroPoint) < chebyshev(rays[j].offset, ZeroPoint)
+	})
+	losCache[sight] = rays
+	return rays
+}
+
+// lineParent returns tile one step closer to zero point on the line from zero point to p
+func lineParent(p image.Point) image.Point {
+	n := chebyshev(p, ZeroPoint)
+	if n <= 1 {
+		return ZeroPoint
+	}
+	return image.Pt(divRound(p.X*(n-1), n), divRound(p.Y*(n-1), n))
+}
+
+func divRound(a, b int) int {
+	if a < 0 {
+		return -((-a + b/2) / b)
+	}
+	return (a + b/2) / b
+}
+
+// SightOffsets returns tiles seen from position relative to it. Tiles higher than the observer
+// and forests not lower than the observer hide tiles behind them, high ground extends sight.
+// Tiles not loaded yet are flat and open.
+func SightOffsets(store Store, position image.Point, sight int) []image.Point {
+	level := groundLevel(store, position)
+	sight += clamp(level*HighGroundSight, 0, MaxHighGroundSight)
+	rays := losRays(sight)
+	visible := make(map[image.Point]bool, len(rays))
+	blocking := make(map[image.Point]bool)
+	offsets := make([]image.Point, 0, len(rays))
+	for _, r := range rays {
+		if r.parent != ZeroPoint {
+			if !visible[r.parent] {
+				continue
+			}
+			blocks, ok := blocking[r.parent]
+			if !ok {
+				blocks = blocksSight(store, position.Add(r.parent), level)
+				blocking[r.parent] = blocks
+			}
+			if blocks {
+				continue
+			}
+		}
+		visible[r.offset] = true
+		offsets = append(offsets, r.offset)
+	}
+	return offsets
+}
+
+// blocksSight tells whether tile hides tiles behind it from observer standing on ground level
+func blocksSight(store Store, p image.Point, level int) bool {
+	t, ok := store.GetTile(p)
+	if !ok || t.Tile == nil {
+		return false
+	}
+	return t.GroundLevel+TerrainOf(t).Height > level
+}
+
+func groundLevel(store Store, p image.Point) int {
+	t, ok := store.GetTile(p)
+	if !ok || t.Tile == nil {
+		return 0
+	}
+	return t.GroundLevel
+}
+
+// updateSight recomputes tiles seen by unit from its position
+func (g *GameLogic) updateSight(u *Unit) {
+	u.ISee = SightOffsets(g.store, u.Position.ImagePoint(), u.Sight)
+}
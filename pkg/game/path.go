@@ -0,0 +1,221 @@
+package game
+
+import (
+	"container/heap"
+	"errors"
+	"image"
+)
+
+const (
+	maxPathNodes       = 4096
+	maxFreePointRadius = 8
+	diagonalCost       = 141 // ~ sqrt(2) * 100
+)
+
+var ErrNoPath = errors.New("no path")
+
+var neighbours = []image.Point{
+	image.Pt(1, 0), image.Pt(-1, 0), image.Pt(0, 1), image.Pt(0, -1),
+	image.Pt(1, 1), image.Pt(1, -1), image.Pt(-1, 1), image.Pt(-1, -1),
+}
+
+type pathNode struct {
+	point image.Point
+	cost  int
+	score int
+	index int
+}
+
+type pathQueue []*pathNode
+
+func (q pathQueue) Len() int { return len(q) }
+
+func (q pathQueue) Less(i, j int) bool { return q[i].score < q[j].score }
+
+func (q pathQueue) Swap(i, j int) {
+	q[i], q[j] = q[j], q[i]
+	q[i].index = i
+	q[j].index = j
+}
+
+func (q *pathQueue) Push(x any) {
+	n := x.(*pathNode)
+	n.index = len(*q)
+	*q = append(*q, n)
+}
+
+func (q *pathQueue) Pop() any {
+	old := *q
+	n := old[len(old)-1]
+	old[len(old)-1] = nil
+	*q = old[:len(old)-1]
+	return n
+}
+
+// FindPath returns path from start to target including both ends, using A* over store tiles.
+// Tiles not loaded yet are assumed to be plain land. Tiles taken by idle units other than unitId are blocked.
+func FindPath(store Store, unitId UnitIdType, start, target image.Point) ([]image.Point, error) {
+	return findPath(store, unitId, start, target, 0)
+}
+
+// findPath searches path to any tile not further than within tiles from target
+func findPath(store Store, unitId UnitIdType, start, target image.Point, within int) ([]image.Point, error) {
+	if chebyshev(start, target) <= within {
+		return []image.Point{start}, nil
+	}
+	if _, ok := stepCost(store, unitId, start, target); !ok && within == 0 {
+		return nil, ErrNoPath
+	}
+
+	nodes := map[image.Point]*pathNode{
+		start: {point: start, score: heuristic(start, target)},
+	}
+	from := make(map[image.Point]image.Point)
+	closed := make(map[image.Point]bool)
+	open := &pathQueue{nodes[start]}
+
+	for open.Len() > 0 && len(closed) < maxPathNodes {
+		current := heap.Pop(open).(*pathNode)
+		if chebyshev(current.point, target) <= within {
+			return buildPath(from, start, current.point), nil
+		}
+		closed[current.point] = true
+
+		for _, v := range neighbours {
+			next := current.point.Add(v)
+			if closed[next] {
+				continue
+			}
+			cost, ok := stepCost(store, unitId, current.point, next)
+			if !ok {
+				continue
+			}
+			if v.X != 0 && v.Y != 0 {
+				// do not cut corners of blocked tiles
+				if _, ok := stepCost(store, unitId, current.point, image.Pt(current.point.X+v.X, current.point.Y)); !ok {
+					continue
+				}
+				if _, ok := stepCost(store, unitId, current.point, image.Pt(current.point.X, current.point.Y+v.Y)); !ok {
+					continue
+				}
+				cost = cost * diagonalCost / plainCost
+			}
+			cost += current.cost
+
+			n, ok := nodes[next]
+			if !ok {
+				n = &pathNode{point: next, cost: cost, score: cost + heuristic(next, target)}
+				nodes[next] = n
+				from[next] = current.point
+				heap.Push(open, n)
+			} else if cost < n.cost {
+				n.cost = cost
+				n.score = cost + heuristic(next, target)
+				from[next] = current.point
+				heap.Fix(open, n.index)
+			}
+		}
+	}
+
+	return nil, ErrNoPath
+}
+
+// PlanPath returns path of the unit to target with movement cost of each step.
+// It does not modify the unit, so the result can be distributed as MoveStepAction.
+func PlanPath(store Store, unit *Unit, target image.Point) ([]image.Point, []int, error) {
+	return PlanPathNear(store, unit, target, 0)
+}
+
+// PlanPathNear is PlanPath which stops not further than within tiles from target, e.g. to approach other unit
+func PlanPathNear(store Store, unit *Unit, target image.Point, within int) ([]image.Point, []int, error) {
+	path, err := findPath(store, unit.Id, unit.Position.ImagePoint(), target, within)
+	if err != nil {
+		return nil, nil, err
+	}
+	costs := make([]int, len(path))
+	costs[0] = plainCost
+	for i := 1; i < len(path); i++ {
+		from, _ := store.GetTile(path[i-1])
+		to, _ := store.GetTile(path[i])
+		costs[i] = MoveCost(from, to)
+	}
+	return path, costs, nil
+}
+
+// FindFreePoint returns passable tile without unit closest to near, points in taken are skipped
+func FindFreePoint(store Store, near image.Point, taken map[image.Point]bool) (image.Point, bool) {
+	for r := 0; r <= maxFreePointRadius; r++ {
+		for x := -r; x <= r; x++ {
+			for y := -r; y <= r; y++ {
+				v := image.Pt(x, y)
+				if chebyshev(ZeroPoint, v) != r {
+					continue
+				}
+				p := near.Add(v)
+				if taken[p] {
+					continue
+				}
+				if t, ok := store.GetTile(p); ok && (t.Unit != nil || t.Building != nil || !TerrainOf(t).Passable) {
+					continue
+				}
+				return p, true
+			}
+		}
+	}
+	return image.Point{}, false
+}
+
+// stepCost returns cost of entering the tile at p from the tile at from and whether it is passable
+func stepCost(store Store, unitId UnitIdType, from, p image.Point) (int, bool) {
+	t, ok := store.GetTile(p)
+	if !ok {
+		return defaultTerrain.Cost, true
+	}
+	if t.Unit != nil && t.Unit.Id != unitId && !t.Unit.IsMoving() {
+		return 0, false
+	}
+	if t.Building != nil {
+		return 0, false
+	}
+	if !TerrainOf(t).Passable {
+		return 0, false
+	}
+	fromTile, _ := store.GetTile(from)
+	return MoveCost(fromTile, t), true
+}
+
+// heuristic is octile distance over plain land
+func heuristic(p, target image.Point) int {
+	dx, dy := abs(target.X-p.X), abs(target.Y-p.Y)
+	if dx < dy {
+		dx, dy = dy, dx
+	}
+	return (dx-dy)*plainCost + dy*diagonalCost
+}
+
+func buildPath(from map[image.Point]image.Point, start, target image.Point) []image.Point {
+	path := []image.Point{target}
+	for p := target; p != start; {
+		p = from[p]
+		path = append(path, p)
+	}
+	for i, j := 0, len(path)-1; i < j; i, j = i+1, j-1 {
+		path[i], path[j] = path[j], path[i]
+	}
+	return path
+}
+
+func chebyshev(p1, p2 image.Point) int {
+	dx, dy := abs(p2.X-p1.X), abs(p2.Y-p1.Y)
+	if dx > dy {
+		return dx
+	}
+	return dy
+}
+
+func abs(x int) int {
+	if x < 0 {
+		return -x
+	}
+	return x
+}
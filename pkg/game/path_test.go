@@ -0,0 +1,129 @@
+package game
+
+import (
+	"errors"
+	"image"
+	"testing"
+
+	"github.com/bmcszk/gptrts/pkg/world"
+)
+
+// testStore returns store with tiles of given land types, other tiles are not loaded
+func testStore(lands map[image.Point]string) Store {
+	store := NewStoreImpl()
+	for p, land := range lands {
+		store.StoreTile(world.Tile{Point: p, LandType: land})
+	}
+	return store
+}
+
+// ring returns land of all tiles around center
+func ring(center image.Point, land string) map[image.Point]string {
+	lands := make(map[image.Point]string)
+	for _, v := range neighbours {
+		lands[center.Add(v)] = land
+	}
+	return lands
+}
+
+func TestFindPath(t *testing.T) {
+	tests := []struct {
+		name    string
+		lands   map[image.Point]string
+		start   image.Point
+		target  image.Point
+		want    []image.Point // exact path, no path is expected when both want and avoided are nil
+		avoided []image.Point // tiles the path must not enter
+	}{
+		{
+			name:   "straight over unloaded tiles",
+			start:  image.Pt(0, 0),
+			target: image.Pt(3, 0),
+			want:   []image.Point{image.Pt(0, 0), image.Pt(1, 0), image.Pt(2, 0), image.Pt(3, 0)},
+		},
+		{
+			name:   "blocked target",
+			lands:  map[image.Point]string{image.Pt(3, 0): "mountain"},
+			start:  image.Pt(0, 0),
+			target: image.Pt(3, 0),
+		},
+		{
+			name:   "no corner cutting",
+			lands:  map[image.Point]string{image.Pt(1, 0): "mountain"},
+			start:  image.Pt(0, 0),
+			target: image.Pt(1, 1),
+			want:   []image.Point{image.Pt(0, 0), image.Pt(0, 1), image.Pt(1, 1)},
+		},
+		{
+			// target is enclosed and unloaded world is endless, search gives up after maxPathNodes
+			name:   "unreachable target",
+			lands:  ring(image.Pt(20, 20), "mountain"),
+			start:  image.Pt(0, 0),
+			target: image.Pt(20, 20),
+		},
+		{
+			name: "detour around expensive terrain",
+			lands: map[image.Point]string{
+				image.Pt(1, 1): "forest",
+				image.Pt(2, 1): "forest",
+				image.Pt(3, 1): "forest",
+			},
+			start:   image.Pt(0, 1),
+			target:  image.Pt(4, 1),
+			avoided: []image.Point{image.Pt(1, 1), image.Pt(2, 1), image.Pt(3, 1)},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			path, err := FindPath(testStore(tt.lands), ZeroUnitId, tt.start, tt.target)
+			if tt.want == nil && tt.avoided == nil {
+				if !errors.Is(err, ErrNoPath) {
+					t.Fatalf("FindPath() = %v, %v, want ErrNoPath", path, err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("FindPath() error %v", err)
+			}
+			if path[0] != tt.start || path[len(path)-1] != tt.target {
+				t.Fatalf("FindPath() = %v, want path from %v to %v", path, tt.start, tt.target)
+			}
+			if tt.want != nil && !samePoints(path, tt.want) {
+				t.Errorf("FindPath() = %v, want %v", path, tt.want)
+			}
+			for _, p := range path {
+				for _, a := range tt.avoided {
+					if p == a {
+						t.Errorf("FindPath() = %v enters %v", path, a)
+					}
+				}
+			}
+		})
+	}
+}
+
+func TestFindPathBlockedByIdleUnit(t *testing.T) {
+	store := testStore(nil)
+	other := testUnit(testUnit2, testPlayer2, image.Pt(2, 0))
+	store.StoreUnit(&other)
+	store.CreateTile(image.Pt(2, 0)).Unit = &other
+
+	if path, err := FindPath(store, testUnit1, image.Pt(0, 0), image.Pt(2, 0)); !errors.Is(err, ErrNoPath) {
+		t.Errorf("FindPath() to tile of other unit = %v, %v, want ErrNoPath", path, err)
+	}
+	if _, err := FindPath(store, testUnit2, image.Pt(0, 0), image.Pt(2, 0)); err != nil {
+		t.Errorf("FindPath() to own tile error %v", err)
+	}
+}
+
+func samePoints(a, b []image.Point) bool {
+	if len(a) != len(b) {
+		return false
+	}
+	for i := range a {
+		if a[i] != b[i] {
+			return false
+		}
+	}
+	return true
+}
@@ -6,13 +6,18 @@ import (
 	"github.com/google/uuid"
 )
 
+var ZeroPlayerId = PlayerIdType(uuid.Nil)
+
 type PlayerIdType uuid.UUID
 
 type Player struct {
-	Id    PlayerIdType
-	Name  string
-	Color color.RGBA
-	Start PF
+	Id        PlayerIdType
+	Name      string
+	Color     color.RGBA
+	Start     PF
+	Team      int
+	Relations []Relation // explicit diplomacy, see StanceTowards
+	Defeated  bool
 }
 
 func NewPlayer(name string) *Player {
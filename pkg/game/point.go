@@ -7,31 +7,42 @@ import (
 
 var ZeroPoint = image.Pt(0, 0)
 
+// Fixed is fixed-point number with FixedOne units per tile.
+// Simulation uses only integer math so every instance of game logic computes the same positions.
+type Fixed int64
+
+const FixedOne Fixed = 1 << 10
+
 type PF struct {
-	X float64
-	Y float64
+	X Fixed
+	Y Fixed
 }
 
-func NewPF(x, y float64) PF {
+func NewPF(x, y Fixed) PF {
 	return PF{
 		X: x, Y: y,
 	}
 }
 
 func ToPF(p image.Point) PF {
-	return NewPF(float64(p.X), float64(p.Y))
+	return NewPF(Fixed(p.X)*FixedOne, Fixed(p.Y)*FixedOne)
 }
 
 func (p PF) ImagePoint() image.Point {
-	return image.Pt(int(p.X), int(p.Y))
+	return image.Pt(int(p.X/FixedOne), int(p.Y/FixedOne))
+}
+
+// Floats returns coordinates in tiles, for rendering only
+func (p PF) Floats() (float64, float64) {
+	return float64(p.X) / float64(FixedOne), float64(p.Y) / float64(FixedOne)
 }
 
 func (p PF) Add(p2 PF) PF {
 	return NewPF(p.X+p2.X, p.Y+p2.Y)
 }
 
-func (p PF) Mul(a float64) PF {
-	return NewPF(p.X*a, p.Y*a)
+func (p PF) Mul(a int) PF {
+	return NewPF(p.X*Fixed(a), p.Y*Fixed(a))
 }
 
 func (p PF) Step(target PF) PF {
@@ -39,30 +50,51 @@ func (p PF) Step(target PF) PF {
 	target = target.Round()
 	dx, dy := target.X-s.X, target.Y-s.Y
 	if dx > 0 {
-		dx = 1
+		dx = FixedOne
 	} else if dx < 0 {
-		dx = -1
+		dx = -FixedOne
 	}
 	if dy > 0 {
-		dy = 1
+		dy = FixedOne
 	} else if dy < 0 {
-		dy = -1
+		dy = -FixedOne
 	}
 	return NewPF(s.X+dx, s.Y+dy)
 }
 
 func (p PF) Round() PF {
-	return NewPF(math.Round(p.X), math.Round(p.Y))
+	return NewPF(roundFixed(p.X), roundFixed(p.Y))
 }
 
 func (p PF) Ints() (int, int) {
 	p = p.Round()
-	return int(p.X), int(p.Y)
+	return int(p.X / FixedOne), int(p.Y / FixedOne)
 }
 
-func (p PF) Dist(target PF) float64 {
+func (p PF) Dist(target PF) Fixed {
 	dx, dy := target.X-p.X, target.Y-p.Y
-	return math.Sqrt(dx*dx + dy*dy)
+	return Fixed(isqrt(int64(dx*dx + dy*dy)))
+}
+
+func roundFixed(v Fixed) Fixed {
+	if v < 0 {
+		return -roundFixed(-v)
+	}
+	return (v + FixedOne/2) / FixedOne * FixedOne
+}
+
+// isqrt returns floor of square root using integer Newton iteration
+func isqrt(n int64) int64 {
+	if n < 2 {
+		return n
+	}
+	x := n
+	y := (x + 1) / 2
+	for y < x {
+		x = y
+		y = (x + n/x) / 2
+	}
+	return x
 }
 
 func Dist(p1 image.Point, p2 image.Point) float64 {
@@ -0,0 +1,73 @@
+package game
+
+import (
+	"image"
+	"testing"
+)
+
+func TestRoundFixed(t *testing.T) {
+	tests := []struct {
+		name string
+		v    Fixed
+		want Fixed
+	}{
+		{"zero", 0, 0},
+		{"whole", 3 * FixedOne, 3 * FixedOne},
+		{"below half", FixedOne/2 - 1, 0},
+		{"half rounds up", FixedOne / 2, FixedOne},
+		{"above half", FixedOne + FixedOne/2 + 1, 2 * FixedOne},
+		{"negative below half", -(FixedOne/2 - 1), 0},
+		{"negative half rounds away from zero", -FixedOne / 2, -FixedOne},
+		{"negative above half", -(FixedOne + FixedOne/2 + 1), -2 * FixedOne},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := roundFixed(tt.v); got != tt.want {
+				t.Errorf("roundFixed(%d) = %d, want %d", tt.v, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestPFInts(t *testing.T) {
+	tests := []struct {
+		name  string
+		p     PF
+		wantX int
+		wantY int
+	}{
+		{"tile", ToPF(image.Pt(2, -3)), 2, -3},
+		{"rounds to nearest tile", NewPF(FixedOne+FixedOne/2, 2*FixedOne-FixedOne/2-1), 2, 1},
+		{"negative rounds away from zero", NewPF(-FixedOne/2, -FixedOne/2+1), -1, 0},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			x, y := tt.p.Ints()
+			if x != tt.wantX || y != tt.wantY {
+				t.Errorf("Ints() = %d, %d, want %d, %d", x, y, tt.wantX, tt.wantY)
+			}
+		})
+	}
+}
+
+func TestPFDist(t *testing.T) {
+	tests := []struct {
+		name string
+		p1   PF
+		p2   PF
+		want Fixed
+	}{
+		{"same point", ToPF(image.Pt(1, 1)), ToPF(image.Pt(1, 1)), 0},
+		{"straight", ToPF(image.Pt(0, 0)), ToPF(image.Pt(0, 5)), 5 * FixedOne},
+		{"pythagorean", ToPF(image.Pt(0, 0)), ToPF(image.Pt(3, 4)), 5 * FixedOne},
+		// sqrt(2) * 1024 = 1448.15, floor is kept
+		{"diagonal", ToPF(image.Pt(0, 0)), ToPF(image.Pt(1, 1)), 1448},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := tt.p1.Dist(tt.p2); got != tt.want {
+				t.Errorf("Dist() = %d, want %d", got, tt.want)
+			}
+		})
+	}
+}
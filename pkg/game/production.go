@@ -0,0 +1,98 @@
+package game
+
+// QueueItem is unit waiting for production in building
+type QueueItem struct {
+	Unit     Unit      // prepared when queued, so every game logic spawns the same unit
+	Progress int       // ticks of production done
+	Time     int       // ticks of production needed
+	Cost     Resources // refunded on cancel
+}
+
+func NewQueueItem(t UnitType, b *Building) QueueItem {
+	unit := NewUnitOfType(t, b.Owner, b.Color, ToPF(b.Rally))
+	time := int(t.BuildTime * TickRate)
+	if time < 1 {
+		time = 1
+	}
+	return QueueItem{
+		Unit: *unit,
+		Time: time,
+		Cost: t.Cost,
+	}
+}
+
+func (b *Building) CanProduce(unitType UnitTypeIdType) bool {
+	for _, t := range b.Produces {
+		if t == unitType {
+			return true
+		}
+	}
+	return false
+}
+
+// QueueIndex returns position of queued unit or -1
+func (b *Building) QueueIndex(unitId UnitIdType) int {
+	for i, item := range b.Queue {
+		if item.Unit.Id == unitId {
+			return i
+		}
+	}
+	return -1
+}
+
+// updateProduction advances production of the first queued unit. Finished unit waits in queue until server spawns it,
+// spawn point depends on tiles around rally point which game logic of a client may not have loaded.
+func (g *GameLogic) updateProduction(b *Building, dispatch DispatchFunc) {
+	if !b.IsAlive() || !b.IsComplete() || len(b.Queue) == 0 {
+		return
+	}
+	item := &b.Queue[0]
+	if item.Progress < item.Time {
+		item.Progress++
+		if item.Progress%progressInterval == 0 || item.Progress == item.Time {
+			dispatch(ProductionProgressAction{
+				Type: ProductionProgressActionType,
+				Payload: ProductionProgressPayload{
+					BuildingId: b.Id,
+					Progress:   item.Progress,
+				},
+			})
+		}
+	}
+}
+
+func (g *GameLogic) handleUnitQueuedAction(action UnitQueuedAction) {
+	b := g.store.GetBuildingById(action.Payload.BuildingId)
+	if b == nil {
+		return
+	}
+	b.Queue = append(b.Queue, action.Payload.Item)
+}
+
+func (g *GameLogic) handleUnitDequeuedAction(action UnitDequeuedAction) {
+	b := g.store.GetBuildingById(action.Payload.BuildingId)
+	if b == nil {
+		return
+	}
+	i := b.QueueIndex(action.Payload.UnitId)
+	if i < 0 {
+		return
+	}
+	b.Queue = append(b.Queue[:i:i], b.Queue[i+1:]...)
+}
+
+func (g *GameLogic) handleProductionProgressAction(action ProductionProgressAction) {
+	b := g.store.GetBuildingById(action.Payload.BuildingId)
+	if b == nil || len(b.Queue) == 0 {
+		return
+	}
+	b.Queue[0].Progress = action.Payload.Progress
+}
+
+func (g *GameLogic) handleRallySetAction(action RallySetAction) {
+	b := g.store.GetBuildingById(action.Payload.BuildingId)
+	if b == nil {
+		return
+	}
+	b.Rally = action.Payload.Point
+}
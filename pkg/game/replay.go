@@ -0,0 +1,114 @@
+package game
+
+import (
+	"bufio"
+	"compress/gzip"
+	"encoding/json"
+	"fmt"
+	"io"
+	"time"
+)
+
+// ReplayVersion - version of replay file format
+const ReplayVersion = 1
+
+// ReplayHeader is the first record of replay file, recorded actions are applied to its state
+type ReplayHeader struct {
+	Version     int
+	Mode        NetworkMode
+	Definitions *Definitions
+	Snapshot    Snapshot
+	Lobby       Lobby
+}
+
+// ReplayEntry is action recorded at time since the start of recording
+type ReplayEntry struct {
+	At     time.Duration
+	Action Action
+}
+
+type replayRecord struct {
+	At     time.Duration
+	Action json.RawMessage
+}
+
+// ReplayWriter writes replay as gzip compressed stream of JSON records, one per line
+type ReplayWriter struct {
+	w     io.WriteCloser
+	zw    *gzip.Writer
+	enc   *json.Encoder
+	start time.Time
+}
+
+func NewReplayWriter(w io.WriteCloser, header ReplayHeader) (*ReplayWriter, error) {
+	zw := gzip.NewWriter(w)
+	rw := &ReplayWriter{
+		w:     w,
+		zw:    zw,
+		enc:   json.NewEncoder(zw),
+		start: time.Now(),
+	}
+	header.Version = ReplayVersion
+	if err := rw.enc.Encode(header); err != nil {
+		return nil, err
+	}
+	return rw, nil
+}
+
+// Record appends action with time since the writer was created
+func (rw *ReplayWriter) Record(action Action) error {
+	return rw.enc.Encode(ReplayEntry{
+		At:     time.Since(rw.start),
+		Action: action,
+	})
+}
+
+// Flush writes buffered records, so replay survives crash of the recorder
+func (rw *ReplayWriter) Flush() error {
+	return rw.zw.Flush()
+}
+
+func (rw *ReplayWriter) Close() error {
+	if err := rw.zw.Close(); err != nil {
+		rw.w.Close()
+		return err
+	}
+	return rw.w.Close()
+}
+
+// ReadReplay reads header and entries of replay. Entries read before an error are returned with it,
+// so truncated replay of crashed recorder can still be watched.
+func ReadReplay(r io.Reader) (*ReplayHeader, []ReplayEntry, error) {
+	zr, err := gzip.NewReader(r)
+	if err != nil {
+		return nil, nil, err
+	}
+	defer zr.Close()
+	dec := json.NewDecoder(bufio.NewReader(zr))
+	var header ReplayHeader
+	if err := dec.Decode(&header); err != nil {
+		return nil, nil, err
+	}
+	if header.Version != ReplayVersion {
+		return nil, nil, fmt.Errorf("replay version %d not supported, want %d", header.Version, ReplayVersion)
+	}
+	if header.Definitions != nil {
+		if err := header.Definitions.Init(); err != nil {
+			return nil, nil, err
+		}
+	}
+	entries := make([]ReplayEntry, 0)
+	for {
+		var record replayRecord
+		if err := dec.Decode(&record); err == io.EOF {
+			return &header, entries, nil
+		} else if err != nil {
+			return &header, entries, err
+		}
+		action, err := UnmarshalAction(record.Action)
+		if err != nil {
+			return &header, entries, err
+		}
+		entries = append(entries, ReplayEntry{At: record.At, Action: action})
+	}
+}
@@ -0,0 +1,194 @@
+package game
+
+import (
+	"image"
+)
+
+const (
+	GatherCapacity = 10           // amount carried by worker in one trip
+	gatherTime     = TickRate * 2 // ticks to fill worker capacity
+)
+
+type ResourceType string
+
+const (
+	Wood  ResourceType = "wood"
+	Stone ResourceType = "stone"
+)
+
+type Resources map[ResourceType]int
+
+// Covers returns true if there is enough resources to pay cost
+func (r Resources) Covers(cost Resources) bool {
+	for t, amount := range cost {
+		if r[t] < amount {
+			return false
+		}
+	}
+	return true
+}
+
+func (r Resources) Add(other Resources) Resources {
+	sum := make(Resources, len(r))
+	for t, amount := range r {
+		sum[t] = amount
+	}
+	for t, amount := range other {
+		sum[t] += amount
+	}
+	return sum
+}
+
+func (r Resources) Sub(other Resources) Resources {
+	diff := make(Resources, len(r))
+	for t, amount := range r {
+		diff[t] = amount
+	}
+	for t, amount := range other {
+		diff[t] -= amount
+	}
+	return diff
+}
+
+// ResourceNode is gatherable resource placed on a tile, depleted node has zero amount
+type ResourceNode struct {
+	Point  image.Point
+	Type   ResourceType
+	Amount int
+}
+
+// ResourceNodeTable maps front style classes without variant number to resource nodes placed on them
+var ResourceNodeTable = map[string]ResourceNode{
+	"forest":   {Type: Wood, Amount: 200},
+	"mountain": {Type: Stone, Amount: 400},
+}
+
+// placeResourceNode puts resource node on the tile derived from its style, unless tile already has one
+func placeResourceNode(t *Tile) {
+	if t.Resource != nil || t.Tile == nil {
+		return
+	}
+	node, ok := ResourceNodeTable[styleClass(t.FrontStyleClass)]
+	if !ok {
+		return
+	}
+	node.Point = t.Point
+	t.Resource = &node
+}
+
+// updateGathering fills worker with resource of adjacent node and delivers it at drop-off point
+func (g *GameLogic) updateGathering(u *Unit, dispatch DispatchFunc) {
+	if !u.IsAlive() || u.Gather == nil || u.IsMoving() {
+		return
+	}
+	p := u.Position.ImagePoint()
+	t, ok := g.store.GetTile(*u.Gather)
+	depleted := !ok || t.Resource == nil || t.Resource.Amount == 0
+
+	if u.MustDeliver(t) {
+		if chebyshev(p, u.Dropoff) > 1 {
+			return
+		}
+		dispatch(ResourceDeliveredAction{
+			Type: ResourceDeliveredActionType,
+			Payload: ResourceDeliveredPayload{
+				PlayerId: u.Owner,
+				UnitId:   u.Id,
+				Type:     u.CarryType,
+				Amount:   u.Carry,
+			},
+		})
+		return
+	}
+
+	if depleted || chebyshev(p, *u.Gather) > 1 {
+		return
+	}
+	u.GatherProgress++
+	if u.GatherProgress < gatherTime {
+		return
+	}
+	amount := GatherCapacity - u.Carry
+	if amount > t.Resource.Amount {
+		amount = t.Resource.Amount
+	}
+	dispatch(ResourceGatheredAction{
+		Type: ResourceGatheredActionType,
+		Payload: ResourceGatheredPayload{
+			UnitId: u.Id,
+			Node: ResourceNode{
+				Point:  t.Resource.Point,
+				Type:   t.Resource.Type,
+				Amount: t.Resource.Amount - amount,
+			},
+			Amount: amount,
+		},
+	})
+}
+
+// MustDeliver tells whether worker goes to drop-off before gathering from the node at tile: it is full,
+// the node is depleted, or the node has other resource than the worker carries
+func (u *Unit) MustDeliver(t *Tile) bool {
+	if u.Carry == 0 {
+		return false
+	}
+	if u.Carry >= GatherCapacity || t == nil || t.Resource == nil || t.Resource.Amount == 0 {
+		return true
+	}
+	return t.Resource.Type != u.CarryType
+}
+
+func (g *GameLogic) handleGatherSetAction(action GatherSetAction) {
+	unit := g.store.GetUnitById(action.Payload.UnitId)
+	if unit == nil {
+		return
+	}
+	unit.Gather = action.Payload.Point
+	unit.Dropoff = action.Payload.Dropoff
+	unit.GatherProgress = 0
+	// state of the node comes with the order, so game logic which has loaded the tile or not gathers the same way
+	if action.Payload.Node != nil {
+		g.updateResourceNode(*action.Payload.Node)
+	}
+}
+
+func (g *GameLogic) handleResourceGatheredAction(action ResourceGatheredAction) {
+	g.updateResourceNode(action.Payload.Node)
+	unit := g.store.GetUnitById(action.Payload.UnitId)
+	if unit == nil {
+		return
+	}
+	unit.Carry += action.Payload.Amount
+	unit.CarryType = action.Payload.Node.Type
+	unit.GatherProgress = 0
+}
+
+func (g *GameLogic) handleResourceDeliveredAction(action ResourceDeliveredAction) {
+	payload := action.Payload
+	resources := g.store.GetResources(payload.PlayerId)
+	g.store.StoreResources(payload.PlayerId, resources.Add(Resources{payload.Type: payload.Amount}))
+	if unit := g.store.GetUnitById(payload.UnitId); unit != nil {
+		unit.Carry = 0
+	}
+}
+
+func (g *GameLogic) handlePlayerResourcesUpdatedAction(action PlayerResourcesUpdatedAction) {
+	g.store.StoreResources(action.Payload.PlayerId, action.Payload.Resources)
+}
+
+func (g *GameLogic) updateResourceNode(node ResourceNode) {
+	t, ok := g.store.GetTile(node.Point)
+	if !ok {
+		t = g.store.CreateTile(node.Point)
+	}
+	t.Resource = &node
+}
+
+// initResourceNode sets node on the tile unless its state is already known
+func (g *GameLogic) initResourceNode(node ResourceNode) {
+	t, ok := g.store.GetTile(node.Point)
+	if ok && t.Resource != nil {
+		return
+	}
+	g.updateResourceNode(node)
+}
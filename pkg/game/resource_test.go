@@ -0,0 +1,37 @@
+package game
+
+import (
+	"image"
+	"testing"
+)
+
+func TestWorkerKeepsLoadOfOtherResource(t *testing.T) {
+	g := NewGameLogic(NewStoreImpl())
+	worker := testUnit(testUnit1, testPlayer1, image.Pt(1, 0))
+	worker.Carry = 5
+	worker.CarryType = Stone
+	node := ResourceNode{Point: image.Pt(2, 0), Type: Wood, Amount: 100}
+	stream := tickStream(3*gatherTime, map[int][]Action{
+		0: {spawn(worker)},
+		1: {GatherSetAction{
+			Type: GatherSetActionType,
+			Payload: GatherSetPayload{
+				UnitId:  testUnit1,
+				Point:   &node.Point,
+				Node:    &node,
+				Dropoff: image.Pt(10, 10),
+			},
+		}},
+	})
+	for _, action := range stream {
+		handleTick(g, action)
+	}
+
+	u := g.store.GetUnitById(testUnit1)
+	if u.Carry != 5 || u.CarryType != Stone {
+		t.Errorf("worker carries %d %s, want 5 %s", u.Carry, u.CarryType, Stone)
+	}
+	if tile, _ := g.store.GetTile(node.Point); tile.Resource.Amount != node.Amount {
+		t.Errorf("node has %d left, want %d", tile.Resource.Amount, node.Amount)
+	}
+}
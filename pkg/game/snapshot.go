@@ -0,0 +1,92 @@
+package game
+
+import (
+	"log"
+
+	"github.com/bmcszk/gptrts/pkg/world"
+)
+
+// Snapshot is full simulation state, it is used to save and resume matches
+type Snapshot struct {
+	Tick          int
+	Players       []Player
+	Stockpiles    []PlayerResourcesPayload
+	Units         []Unit
+	Buildings     []Building
+	Tiles         []world.Tile   // terrain loaded so far
+	ResourceNodes []ResourceNode // state of resource nodes on the tiles
+	Result        *GameOverPayload
+}
+
+// Snapshot returns copy of the state, units and buildings are sorted by id
+func (g *GameLogic) Snapshot() Snapshot {
+	s := Snapshot{
+		Tick:          g.tick,
+		Players:       make([]Player, 0),
+		Stockpiles:    make([]PlayerResourcesPayload, 0),
+		Units:         make([]Unit, 0),
+		Buildings:     make([]Building, 0),
+		Tiles:         make([]world.Tile, 0),
+		ResourceNodes: make([]ResourceNode, 0),
+		Result:        g.result,
+	}
+	for _, p := range g.store.GetAllPlayers() {
+		s.Players = append(s.Players, *p)
+		s.Stockpiles = append(s.Stockpiles, PlayerResourcesPayload{
+			PlayerId:  p.Id,
+			Resources: g.store.GetResources(p.Id),
+		})
+	}
+	for _, u := range sortedUnits(g.store.GetAllUnits()) {
+		s.Units = append(s.Units, *u)
+	}
+	for _, b := range sortedBuildings(g.store.GetAllBuildings()) {
+		s.Buildings = append(s.Buildings, *b)
+	}
+	for _, t := range g.store.GetAllTiles() {
+		if t.Loaded {
+			s.Tiles = append(s.Tiles, *t.Tile)
+		}
+		if t.Resource != nil {
+			s.ResourceNodes = append(s.ResourceNodes, *t.Resource)
+		}
+	}
+	return s
+}
+
+// Restore puts state of the snapshot to empty store
+func (g *GameLogic) Restore(s Snapshot) {
+	g.tick = s.Tick
+	g.result = s.Result
+	for _, t := range s.Tiles {
+		g.store.StoreTile(t)
+	}
+	for _, node := range s.ResourceNodes {
+		g.updateResourceNode(node)
+	}
+	for _, p := range s.Players {
+		g.store.StorePlayer(p)
+	}
+	for _, stockpile := range s.Stockpiles {
+		g.store.StoreResources(stockpile.PlayerId, stockpile.Resources)
+	}
+	for _, b := range s.Buildings {
+		building := b
+		g.store.StoreBuilding(&building)
+		g.placeBuilding(&building)
+	}
+	for _, u := range s.Units {
+		unit := u
+		g.store.StoreUnit(&unit)
+		if err := g.placeUnit(&unit); err != nil {
+			log.Println(err)
+		}
+		// reserved next step
+		if unit.IsMoving() {
+			if err := g.placeUnit(&unit, unit.Path[unit.Step]); err != nil {
+				log.Println(err)
+			}
+		}
+		g.updateSight(&unit)
+	}
+}
@@ -9,38 +9,54 @@ import (
 
 type Store interface {
 	StoreUnit(unit *Unit)
+	RemoveUnit(id UnitIdType)
 	GetUnitById(id UnitIdType) *Unit
 	GetAllUnits() []*Unit
 	GetUnitsByPlayerId(id PlayerIdType) []*Unit
 
+	StoreBuilding(building *Building)
+	RemoveBuilding(id BuildingIdType)
+	GetBuildingById(id BuildingIdType) *Building
+	GetAllBuildings() []*Building
+	GetBuildingsByPlayerId(id PlayerIdType) []*Building
+
 	GetPlayer(id PlayerIdType) (*Player, bool)
 	GetAllPlayers() []*Player
 	StorePlayer(player Player)
+	GetResources(id PlayerIdType) Resources
+	StoreResources(id PlayerIdType, resources Resources)
 
 	GetTilesByUnitId(id UnitIdType) []*Tile
 	StoreTile(tile world.Tile) *Tile
 	GetTile(image.Point) (*Tile, bool)
+	GetAllTiles() []*Tile
 	CreateTile(image.Point) *Tile
 	GetTilesByRect(rect image.Rectangle) map[image.Point]*Tile
 }
 
 type StoreImpl struct {
-	unitMux   *sync.Mutex
-	tilesMux  *sync.Mutex
-	playerMux *sync.Mutex
-	units     map[UnitIdType]*Unit
-	tiles     map[image.Point]*Tile
-	players   map[PlayerIdType]*Player
+	unitMux     *sync.Mutex
+	buildingMux *sync.Mutex
+	tilesMux    *sync.Mutex
+	playerMux   *sync.Mutex
+	units       map[UnitIdType]*Unit
+	buildings   map[BuildingIdType]*Building
+	tiles       map[image.Point]*Tile
+	players     map[PlayerIdType]*Player
+	resources   map[PlayerIdType]Resources
 }
 
 func NewStoreImpl() *StoreImpl {
 	return &StoreImpl{
-		unitMux:   &sync.Mutex{},
-		tilesMux:  &sync.Mutex{},
-		playerMux: &sync.Mutex{},
-		units:     make(map[UnitIdType]*Unit),
-		tiles:     make(map[image.Point]*Tile),
-		players:   make(map[PlayerIdType]*Player),
+		unitMux:     &sync.Mutex{},
+		buildingMux: &sync.Mutex{},
+		tilesMux:    &sync.Mutex{},
+		playerMux:   &sync.Mutex{},
+		units:       make(map[UnitIdType]*Unit),
+		buildings:   make(map[BuildingIdType]*Building),
+		tiles:       make(map[image.Point]*Tile),
+		players:     make(map[PlayerIdType]*Player),
+		resources:   make(map[PlayerIdType]Resources),
 	}
 }
 
@@ -72,12 +88,58 @@ func (s *StoreImpl) StoreUnit(unit *Unit) {
 	s.units[unit.Id] = unit
 }
 
+func (s *StoreImpl) RemoveUnit(id UnitIdType) {
+	s.unitMux.Lock()
+	defer s.unitMux.Unlock()
+	delete(s.units, id)
+}
+
 func (s *StoreImpl) GetUnitById(id UnitIdType) *Unit {
 	s.unitMux.Lock()
 	defer s.unitMux.Unlock()
 	return s.units[id]
 }
 
+func (s *StoreImpl) StoreBuilding(building *Building) {
+	s.buildingMux.Lock()
+	defer s.buildingMux.Unlock()
+	s.buildings[building.Id] = building
+}
+
+func (s *StoreImpl) RemoveBuilding(id BuildingIdType) {
+	s.buildingMux.Lock()
+	defer s.buildingMux.Unlock()
+	delete(s.buildings, id)
+}
+
+func (s *StoreImpl) GetBuildingById(id BuildingIdType) *Building {
+	s.buildingMux.Lock()
+	defer s.buildingMux.Unlock()
+	return s.buildings[id]
+}
+
+func (s *StoreImpl) GetAllBuildings() []*Building {
+	s.buildingMux.Lock()
+	defer s.buildingMux.Unlock()
+	r := make([]*Building, 0, len(s.buildings))
+	for _, b := range s.buildings {
+		r = append(r, b)
+	}
+	return r
+}
+
+func (s *StoreImpl) GetBuildingsByPlayerId(id PlayerIdType) []*Building {
+	s.buildingMux.Lock()
+	defer s.buildingMux.Unlock()
+	r := make([]*Building, 0)
+	for _, b := range s.buildings {
+		if b.Owner == id {
+			r = append(r, b)
+		}
+	}
+	return r
+}
+
 func (s *StoreImpl) GetPlayer(id PlayerIdType) (*Player, bool) {
 	s.playerMux.Lock()
 	defer s.playerMux.Unlock()
@@ -103,6 +165,21 @@ func (s *StoreImpl) StorePlayer(player Player) {
 	s.players[player.Id] = &player
 }
 
+func (s *StoreImpl) GetResources(id PlayerIdType) Resources {
+	s.playerMux.Lock()
+	defer s.playerMux.Unlock()
+	if r, ok := s.resources[id]; ok {
+		return r
+	}
+	return Resources{}
+}
+
+func (s *StoreImpl) StoreResources(id PlayerIdType, resources Resources) {
+	s.playerMux.Lock()
+	defer s.playerMux.Unlock()
+	s.resources[id] = resources
+}
+
 func (s *StoreImpl) GetTilesByUnitId(id UnitIdType) []*Tile {
 	s.tilesMux.Lock()
 	defer s.tilesMux.Unlock()
@@ -124,9 +201,11 @@ func (s *StoreImpl) StoreTile(tile world.Tile) *Tile {
 func (s *StoreImpl) storeTile(tile world.Tile) *Tile {
 	if t, ok := s.tiles[tile.Point]; ok {
 		t.Tile = &tile
+		t.Loaded = true
 	} else {
 		s.tiles[tile.Point] = &Tile{
-			Tile: &tile,
+			Tile:   &tile,
+			Loaded: true,
 		}
 	}
 	return s.tiles[tile.Point]
@@ -141,10 +220,28 @@ func (s *StoreImpl) GetTile(point image.Point) (*Tile, bool) {
 	return nil, false
 }
 
+func (s *StoreImpl) GetAllTiles() []*Tile {
+	s.tilesMux.Lock()
+	defer s.tilesMux.Unlock()
+	r := make([]*Tile, 0, len(s.tiles))
+	for _, t := range s.tiles {
+		r = append(r, t)
+	}
+	return r
+}
+
+// CreateTile returns tile at point, tile which does not exist is created as not loaded plain land
 func (s *StoreImpl) CreateTile(point image.Point) *Tile {
-	return s.StoreTile(world.Tile{
-		Point: point,
-	})
+	s.tilesMux.Lock()
+	defer s.tilesMux.Unlock()
+	if t, ok := s.tiles[point]; ok {
+		return t
+	}
+	t := &Tile{
+		Tile: &world.Tile{Point: point},
+	}
+	s.tiles[point] = t
+	return t
 }
 
 func (s *StoreImpl) GetTilesByRect(rect image.Rectangle) map[image.Point]*Tile {
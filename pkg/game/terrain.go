@@ -0,0 +1,85 @@
+package game
+
+import (
+	"strings"
+
+	"github.com/bmcszk/gptrts/pkg/world"
+)
+
+// Terrain describes how units move over a land type.
+// Cost is expressed in percent of plain land, so 200 means twice as slow.
+// Height is added to ground level when it comes to blocking sight.
+type Terrain struct {
+	Passable bool
+	Cost     int
+	Height   int
+}
+
+const (
+	plainCost   = 100
+	minMoveCost = 50
+)
+
+var defaultTerrain = Terrain{Passable: true, Cost: plainCost}
+
+// TerrainTable maps land types (and front style classes without variant number) to terrain rules.
+var TerrainTable = map[string]Terrain{
+	"plain":    {Passable: true, Cost: plainCost},
+	"grass":    {Passable: true, Cost: plainCost},
+	"sand":     {Passable: true, Cost: 130},
+	"hill":     {Passable: true, Cost: 160},
+	"forest":   {Passable: true, Cost: 200, Height: 1},
+	"mountain": {Passable: false},
+	"river":    {Passable: false},
+	"lake":     {Passable: false},
+	"sea":      {Passable: false},
+	"water":    {Passable: false},
+}
+
+var (
+	// SlopeCost is added to movement cost per ground level climbed and subtracted per level descended
+	SlopeCost = 25
+	// WaterDepthCost is added to movement cost per water level above ground
+	WaterDepthCost = 50
+)
+
+func TerrainOf(t *Tile) Terrain {
+	if t == nil || t.Tile == nil {
+		return defaultTerrain
+	}
+	return terrainOf(t.Tile)
+}
+
+func terrainOf(t *world.Tile) Terrain {
+	if terrain, ok := TerrainTable[styleClass(t.FrontStyleClass)]; ok {
+		return terrain
+	}
+	if terrain, ok := TerrainTable[strings.ToLower(t.LandType)]; ok {
+		return terrain
+	}
+	return defaultTerrain
+}
+
+// styleClass strips variant number, e.g. forest2 -> forest
+func styleClass(class string) string {
+	return strings.TrimRight(class, "0123456789")
+}
+
+// MoveCost returns cost of moving from one tile to adjacent one in percent of plain land.
+// Land type of destination tile, ground level difference and water depth are taken into account.
+func MoveCost(from, to *Tile) int {
+	cost := TerrainOf(to).Cost
+	if to == nil || to.Tile == nil {
+		return cost
+	}
+	if to.WaterLevel != nil && *to.WaterLevel > to.GroundLevel {
+		cost += (*to.WaterLevel - to.GroundLevel) * WaterDepthCost
+	}
+	if from != nil && from.Tile != nil {
+		cost += (to.GroundLevel - from.GroundLevel) * SlopeCost
+	}
+	if cost < minMoveCost {
+		cost = minMoveCost
+	}
+	return cost
+}
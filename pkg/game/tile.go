@@ -6,6 +6,9 @@ import (
 
 type Tile struct {
 	*world.Tile
-	Unit    *Unit
-	Visible bool
+	Loaded     bool // terrain came from world service, tiles created only to hold units or buildings are not loaded
+	Unit       *Unit
+	Visibility Visibility // visibility for local player, see Fog
+	Resource   *ResourceNode
+	Building   *Building
 }
@@ -3,37 +3,32 @@ package game
 import (
 	"image"
 	"image/color"
-	"math"
 
 	"github.com/google/uuid"
 )
 
 const (
-	UnitSpeed = 0.1
+	UnitSpeed = FixedOne / 10 // distance per update on plain land
+	TickRate  = 60            // simulation updates per second
 )
 
 var ZeroUnitId = UnitIdType(uuid.Nil)
 
-const defaultSight = 5
-
-var defaultISee []image.Point
+const (
+	defaultSight    = 5
+	defaultHP       = 100
+	defaultDamage   = 10
+	defaultRange    = FixedOne * 3 / 2 // adjacent tiles including diagonal
+	defaultCooldown = TickRate         // ticks between attacks
+)
 
-func init() {
-	defaultISee = make([]image.Point, 0, defaultSight)
-	for x := -5; x <= 5; x++ {
-		for y := -5; y <= 5; y++ {
-			p := image.Pt(x, y)
-			if Dist(p, ZeroPoint) <= defaultSight {
-				defaultISee = append(defaultISee, image.Pt(x, y))
-			}
-		}
-	}
-}
+var defaultISee = visionOffsets(defaultSight)
 
 type UnitIdType uuid.UUID
 
 type Unit struct {
 	Id       UnitIdType
+	Type     UnitTypeIdType
 	Owner    PlayerIdType
 	Color    color.RGBA
 	Position PF
@@ -41,8 +36,28 @@ type Unit struct {
 	Selected bool
 	Velocity PF `json:"-"`
 	Path     []image.Point
+	Costs    []int // movement cost of entering each path step, see MoveCost
 	Step     int
-	ISee     []image.Point
+	Seq      int   // last move command of the owner applied to the unit, see MoveStartPayload
+	Speed    Fixed // distance per update on plain land
+	Sight    int
+	ISee     []image.Point `json:"-"` // tiles in line of sight relative to position, see SightOffsets
+	HP       int
+	MaxHP    int
+	Damage   int
+	Range    Fixed      // attack range
+	Cooldown int        // ticks between attacks
+	Reload   int        // ticks left to next attack
+	Target   UnitIdType // unit to attack
+	// building to attack
+	TargetBuilding BuildingIdType
+	Abilities      []string
+	// gathering
+	Gather         *image.Point // resource node to gather from
+	Dropoff        image.Point  // where gathered resources are delivered
+	Carry          int
+	CarryType      ResourceType
+	GatherProgress int // ticks spent gathering
 }
 
 func NewUnit(owner PlayerIdType, c color.RGBA, position PF, width, height int) *Unit {
@@ -52,7 +67,14 @@ func NewUnit(owner PlayerIdType, c color.RGBA, position PF, width, height int) *
 		Color:    c,
 		Position: position,
 		Size:     image.Pt(width, height),
+		Speed:    UnitSpeed,
+		Sight:    defaultSight,
 		ISee:     defaultISee,
+		HP:       defaultHP,
+		MaxHP:    defaultHP,
+		Damage:   defaultDamage,
+		Range:    defaultRange,
+		Cooldown: defaultCooldown,
 	}
 }
 
@@ -60,60 +82,85 @@ func NewUnitId() UnitIdType {
 	return UnitIdType(uuid.New())
 }
 
-func (u *Unit) MoveTo(target image.Point) {
+func (u *Unit) MoveTo(target image.Point, store Store) error {
 	if len(u.Path) > 0 && target == u.Path[len(u.Path)-1] {
-		return
+		return nil
+	}
+	path, costs, err := PlanPath(store, u, target)
+	if err != nil {
+		return err
 	}
-	path := []image.Point{u.Position.ImagePoint()}
-	path = plan(path, target)
 	u.Path = path
+	u.Costs = costs
 	u.Step = 0
+	return nil
+}
+
+func (u *Unit) HasAbility(ability string) bool {
+	for _, a := range u.Abilities {
+		if a == ability {
+			return true
+		}
+	}
+	return false
+}
+
+func (u *Unit) IsAlive() bool {
+	return u.HP > 0
+}
+
+func (u *Unit) IsMoving() bool {
+	return len(u.Path) > u.Step
 }
 
 func (u *Unit) Set(unit Unit) {
 	u.Step = unit.Step
 	u.Position = unit.Position
 	u.Path = unit.Path
-}
-
-func plan(path []image.Point, target image.Point) []image.Point {
-	prevStep := path[len(path)-1]
-	if prevStep == target {
-		return path
-	}
-	nextStep := NextStep(prevStep, target)
-	path = append(path, nextStep)
-	return plan(path, target)
+	u.Costs = unit.Costs
 }
 
 func (u *Unit) Update(dispatch DispatchFunc) {
-	if len(u.Path) <= u.Step {
+	if !u.IsMoving() {
 		return
 	}
+	speed := u.speed()
 	// Move the unit towards the target position
-	dx, dy := float64(u.Path[u.Step].X)-u.Position.X, float64(u.Path[u.Step].Y)-u.Position.Y
-	dist := math.Sqrt(dx*dx + dy*dy)
+	target := ToPF(u.Path[u.Step])
+	dx, dy := target.X-u.Position.X, target.Y-u.Position.Y
+	dist := u.Position.Dist(target)
 
-	if dist < 0.1 {
+	if dist <= speed {
 		u.Velocity = NewPF(0, 0)
-		u.Position = ToPF(u.Path[u.Step])
+		u.Position = target
 		u.Step = u.Step + 1
-		dispatch(u.newMoveAction())
+		dispatch(u.NewMoveStepAction())
 	} else {
-		dx, dy = dx/dist, dy/dist
-		u.Velocity = NewPF(dx*UnitSpeed, dy*UnitSpeed)
+		u.Velocity = NewPF(dx*speed/dist, dy*speed/dist)
 		u.Position = u.Position.Add(u.Velocity)
 	}
 }
 
-func (u *Unit) newMoveAction() MoveStepAction {
+// speed returns distance travelled per update on the current path segment
+func (u *Unit) speed() Fixed {
+	cost := plainCost
+	if u.Step < len(u.Costs) {
+		cost = u.Costs[u.Step]
+	}
+	return u.Speed * plainCost / Fixed(cost)
+}
+
+// NewMoveStepAction returns action describing current movement state of the unit
+func (u *Unit) NewMoveStepAction() MoveStepAction {
 	return MoveStepAction{
 		Type: MoveStepActionType,
 		Payload: MoveStepPayload{
 			UnitId:   u.Id,
 			Position: u.Position,
 			Path:     u.Path,
+			Costs:    u.Costs,
 			Step:     u.Step,
+			Seq:      u.Seq,
 		},
 	}
 }
@@ -0,0 +1,68 @@
+package game
+
+import (
+	"image"
+	"image/color"
+	"sync"
+)
+
+type UnitTypeIdType string
+
+const GatherAbility = "gather"
+
+// UnitType is template of units, values are in human friendly units and converted to simulation ones by NewUnitOfType
+type UnitType struct {
+	Id        UnitTypeIdType `json:"id"`
+	Name      string         `json:"name"`
+	Width     int            `json:"width"`    // pixels
+	Height    int            `json:"height"`   // pixels
+	Speed     float64        `json:"speed"`    // tiles per second on plain land
+	Sight     int            `json:"sight"`    // tiles
+	HP        int            `json:"hp"`       // hit points
+	Damage    int            `json:"damage"`   // per attack
+	Range     float64        `json:"range"`    // tiles
+	Cooldown  float64        `json:"cooldown"` // seconds between attacks
+	Cost      Resources      `json:"cost"`
+	BuildTime float64        `json:"buildTime"` // seconds of production
+	Abilities []string       `json:"abilities"`
+}
+
+func NewUnitOfType(t UnitType, owner PlayerIdType, c color.RGBA, position PF) *Unit {
+	u := NewUnit(owner, c, position, t.Width, t.Height)
+	u.Type = t.Id
+	u.Speed = Fixed(t.Speed * float64(FixedOne) / TickRate)
+	u.Sight = t.Sight
+	u.ISee = visionOffsets(t.Sight)
+	u.HP = t.HP
+	u.MaxHP = t.HP
+	u.Damage = t.Damage
+	u.Range = Fixed(t.Range * float64(FixedOne))
+	u.Cooldown = int(t.Cooldown * TickRate)
+	u.Abilities = t.Abilities
+	return u
+}
+
+var (
+	visionCache = map[int][]image.Point{}
+	visionMux   = &sync.Mutex{}
+)
+
+// visionOffsets returns tiles in sight radius relative to unit position
+func visionOffsets(sight int) []image.Point {
+	visionMux.Lock()
+	defer visionMux.Unlock()
+	if offsets, ok := visionCache[sight]; ok {
+		return offsets
+	}
+	offsets := make([]image.Point, 0)
+	for x := -sight; x <= sight; x++ {
+		for y := -sight; y <= sight; y++ {
+			p := image.Pt(x, y)
+			if Dist(p, ZeroPoint) <= float64(sight) {
+				offsets = append(offsets, p)
+			}
+		}
+	}
+	visionCache[sight] = offsets
+	return offsets
+}
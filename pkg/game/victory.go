@@ -0,0 +1,58 @@
+package game
+
+type VictoryCondition string
+
+const (
+	AnnihilationCondition VictoryCondition = "annihilation" // player without units and buildings is defeated
+	BuildingCondition     VictoryCondition = "building"     // player who lost all key buildings is defeated
+	ScoreCondition        VictoryCondition = "score"        // player with highest score wins when time is up
+)
+
+// Victory configures how a match ends
+type Victory struct {
+	Conditions []VictoryCondition   `json:"conditions"`
+	Buildings  []BuildingTypeIdType `json:"buildings"` // key building types of building condition
+	TimeLimit  float64              `json:"timeLimit"` // seconds, used by score condition
+}
+
+func (v Victory) Has(condition VictoryCondition) bool {
+	for _, c := range v.Conditions {
+		if c == condition {
+			return true
+		}
+	}
+	return false
+}
+
+// IsKeyBuilding tells whether losing all buildings of the type defeats player under building condition
+func (v Victory) IsKeyBuilding(t BuildingTypeIdType) bool {
+	for _, b := range v.Buildings {
+		if b == t {
+			return true
+		}
+	}
+	return false
+}
+
+type PlayerScore struct {
+	PlayerId PlayerIdType
+	Score    int
+}
+
+func (g *GameLogic) handlePlayerDefeatedAction(action PlayerDefeatedAction) {
+	player, ok := g.store.GetPlayer(action.Payload.PlayerId)
+	if !ok {
+		return
+	}
+	player.Defeated = true
+}
+
+func (g *GameLogic) handleGameOverAction(action GameOverAction) {
+	result := action.Payload
+	g.result = &result
+}
+
+// Result returns how the match ended or nil while it is in progress
+func (g *GameLogic) Result() *GameOverPayload {
+	return g.result
+}
@@ -0,0 +1,80 @@
+package game
+
+import (
+	"image"
+	"log"
+)
+
+// Vision returns tiles seen by the unit
+func (u *Unit) Vision() []image.Point {
+	position := u.Position.ImagePoint()
+	points := make([]image.Point, 0, len(u.ISee))
+	for _, v := range u.ISee {
+		points = append(points, position.Add(v))
+	}
+	return points
+}
+
+// VisibleTiles returns tiles seen by player, allies share vision
+func VisibleTiles(store Store, playerId PlayerIdType) map[image.Point]bool {
+	visible := make(map[image.Point]bool)
+	for _, u := range store.GetAllUnits() {
+		if !SharesVision(store, u.Owner, playerId) {
+			continue
+		}
+		for _, p := range u.Vision() {
+			visible[p] = true
+		}
+	}
+	for _, b := range store.GetAllBuildings() {
+		if !SharesVision(store, b.Owner, playerId) {
+			continue
+		}
+		for _, p := range b.Vision() {
+			visible[p] = true
+		}
+	}
+	return visible
+}
+
+// SeesUnit tells whether player with visible tiles sees the unit, units of allies are always seen
+func SeesUnit(store Store, visible map[image.Point]bool, playerId PlayerIdType, u *Unit) bool {
+	return SharesVision(store, u.Owner, playerId) || visible[u.Position.ImagePoint()]
+}
+
+// SeesBuilding tells whether player with visible tiles sees any tile of the building, buildings of allies are always seen
+func SeesBuilding(store Store, visible map[image.Point]bool, playerId PlayerIdType, b *Building) bool {
+	return SharesVision(store, b.Owner, playerId) || SeesArea(visible, b.Rect())
+}
+
+// SeesArea tells whether any tile of the area is visible
+func SeesArea(visible map[image.Point]bool, area image.Rectangle) bool {
+	for _, p := range FootprintPoints(area.Min, area.Size()) {
+		if visible[p] {
+			return true
+		}
+	}
+	return false
+}
+
+// handleUnitEnteredVisionAction stores unit which became visible with its current state
+func (g *GameLogic) handleUnitEnteredVisionAction(action UnitEnteredVisionAction) {
+	unit := action.Payload.Unit
+	for _, tile := range g.store.GetTilesByUnitId(unit.Id) {
+		tile.Unit = nil
+	}
+	g.store.StoreUnit(&unit)
+	if err := g.placeUnit(&unit); err != nil {
+		log.Println(err)
+	}
+	g.updateSight(&unit)
+}
+
+// handleUnitLeftVisionAction forgets unit which is not visible anymore
+func (g *GameLogic) handleUnitLeftVisionAction(action UnitLeftVisionAction) {
+	id := action.Payload.UnitId
+	for _, tile := range g.store.GetTilesByUnitId(id) {
+		tile.Unit = nil
+	}
+	g.store.RemoveUnit(id)
+}
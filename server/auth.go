@@ -0,0 +1,91 @@
+package main
+
+import (
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"strings"
+
+	"github.com/bmcszk/gptrts/pkg/game"
+)
+
+const secretSize = 32
+
+// session is identity of player carried by token
+type session struct {
+	PlayerId game.PlayerIdType
+	Name     string
+}
+
+// authenticator issues and verifies HMAC signed session tokens
+type authenticator struct {
+	secret []byte
+}
+
+// loadSecret reads key of token signatures, new key is generated and stored when file does not exist,
+// so tokens stay valid when server restarts
+func loadSecret(path string) ([]byte, error) {
+	secret, err := os.ReadFile(path)
+	if err == nil {
+		if len(secret) < secretSize {
+			return nil, fmt.Errorf("secret %s: too short, %d bytes min", path, secretSize)
+		}
+		return secret, nil
+	}
+	if !errors.Is(err, os.ErrNotExist) {
+		return nil, err
+	}
+	secret = make([]byte, secretSize)
+	if _, err := rand.Read(secret); err != nil {
+		return nil, err
+	}
+	if err := os.WriteFile(path, secret, 0o600); err != nil {
+		return nil, err
+	}
+	return secret, nil
+}
+
+// issue returns token of session, it is base64 encoded session and its signature
+func (a *authenticator) issue(s session) (string, error) {
+	payload, err := json.Marshal(s)
+	if err != nil {
+		return "", err
+	}
+	enc := base64.RawURLEncoding
+	return enc.EncodeToString(payload) + "." + enc.EncodeToString(a.sign(payload)), nil
+}
+
+func (a *authenticator) verify(token string) (session, error) {
+	var s session
+	enc := base64.RawURLEncoding
+	encPayload, encSig, ok := strings.Cut(token, ".")
+	if !ok {
+		return s, errors.New("token malformed")
+	}
+	payload, err := enc.DecodeString(encPayload)
+	if err != nil {
+		return s, errors.New("token malformed")
+	}
+	sig, err := enc.DecodeString(encSig)
+	if err != nil {
+		return s, errors.New("token malformed")
+	}
+	if !hmac.Equal(sig, a.sign(payload)) {
+		return s, errors.New("token signature invalid")
+	}
+	if err := json.Unmarshal(payload, &s); err != nil {
+		return s, errors.New("token malformed")
+	}
+	return s, nil
+}
+
+func (a *authenticator) sign(payload []byte) []byte {
+	mac := hmac.New(sha256.New, a.secret)
+	mac.Write(payload)
+	return mac.Sum(nil)
+}
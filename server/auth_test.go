@@ -0,0 +1,59 @@
+package main
+
+import (
+	"encoding/base64"
+	"strings"
+	"testing"
+
+	"github.com/bmcszk/gptrts/pkg/game"
+)
+
+func TestAuthenticatorVerify(t *testing.T) {
+	a := &authenticator{secret: []byte(strings.Repeat("k", secretSize))}
+	s := session{PlayerId: game.PlayerIdType{1}, Name: "alice"}
+	token, err := a.issue(s)
+	if err != nil {
+		t.Fatal(err)
+	}
+	payload, sig, _ := strings.Cut(token, ".")
+	enc := base64.RawURLEncoding
+	forged := enc.EncodeToString([]byte(`{"PlayerId":"00000000-0000-0000-0000-000000000002","Name":"alice"}`))
+	other := &authenticator{secret: []byte(strings.Repeat("x", secretSize))}
+	otherToken, err := other.issue(s)
+	if err != nil {
+		t.Fatal(err)
+	}
+	_, otherSig, _ := strings.Cut(otherToken, ".")
+
+	tests := []struct {
+		name    string
+		token   string
+		wantErr string
+	}{
+		{"valid", token, ""},
+		{"tampered payload", forged + "." + sig, "token signature invalid"},
+		{"tampered signature", payload + "." + enc.EncodeToString([]byte("signature")), "token signature invalid"},
+		{"signed with other secret", payload + "." + otherSig, "token signature invalid"},
+		{"no separator", payload + sig, "token malformed"},
+		{"payload not base64", "!!." + sig, "token malformed"},
+		{"signature not base64", payload + ".!!", "token malformed"},
+		{"empty", "", "token malformed"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := a.verify(tt.token)
+			if tt.wantErr == "" {
+				if err != nil {
+					t.Fatalf("verify() error %v", err)
+				}
+				if got != s {
+					t.Errorf("verify() = %v, want %v", got, s)
+				}
+				return
+			}
+			if err == nil || err.Error() != tt.wantErr {
+				t.Errorf("verify() error %v, want %q", err, tt.wantErr)
+			}
+		})
+	}
+}
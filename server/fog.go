@@ -0,0 +1,226 @@
+package main
+
+import (
+	"image"
+
+	"github.com/bmcszk/gptrts/pkg/game"
+)
+
+// knownBuilding is building sent to clients of player, visible is as of the last vision update
+type knownBuilding struct {
+	visible bool
+	area    image.Rectangle
+}
+
+// Observes tells whether action may be sent to player, updates of units go only to players who know the unit.
+// Player knows units seen since the last enter vision action, see UpdateVision. Buildings are updated only while
+// seen, clients keep last seen state. Production and stockpiles are sent to owner and allies only.
+// Authoritative mode only, in lockstep mode every client simulates whole world.
+func (g *serverGame) Observes(playerId game.PlayerIdType, action game.Action) bool {
+	switch a := action.(type) {
+	case game.SpawnUnitAction:
+		unit := a.Payload
+		if !game.SeesUnit(g.store, game.VisibleTiles(g.store, playerId), playerId, &unit) {
+			return false
+		}
+		g.knownBy(playerId)[unit.Id] = true
+		return true
+	case game.UnitDiedAction:
+		known := g.knownBy(playerId)
+		if !known[a.Payload.UnitId] {
+			return false
+		}
+		delete(known, a.Payload.UnitId)
+		return true
+	case game.MoveStepAction:
+		return g.knownBy(playerId)[a.Payload.UnitId]
+	case game.MoveStopAction:
+		return g.knownBy(playerId)[a.Payload]
+	case game.TargetSetAction:
+		return g.knownBy(playerId)[a.Payload.UnitId]
+	case game.UnitDamagedAction:
+		return g.knownBy(playerId)[a.Payload.UnitId]
+	case game.GatherSetAction:
+		return g.knownBy(playerId)[a.Payload.UnitId]
+	case game.ResourceGatheredAction:
+		return g.knownBy(playerId)[a.Payload.UnitId]
+	case game.ResourceDeliveredAction:
+		return g.knownBy(playerId)[a.Payload.UnitId]
+	case game.PlayerResourcesUpdatedAction:
+		return game.SharesVision(g.store, a.Payload.PlayerId, playerId)
+	case game.BuildingPlacedAction:
+		b := a.Payload
+		if !game.SeesBuilding(g.store, game.VisibleTiles(g.store, playerId), playerId, &b) {
+			return false
+		}
+		g.buildingsKnownBy(playerId)[b.Id] = knownBuilding{visible: true, area: b.Rect()}
+		return true
+	case game.BuildingProgressAction:
+		return g.buildingsKnownBy(playerId)[a.Payload.BuildingId].visible
+	case game.BuildingDamagedAction:
+		return g.buildingsKnownBy(playerId)[a.Payload.BuildingId].visible
+	case game.BuildingDestroyedAction:
+		known := g.buildingsKnownBy(playerId)
+		if !known[a.Payload.BuildingId].visible {
+			return false
+		}
+		delete(known, a.Payload.BuildingId)
+		return true
+	case game.UnitQueuedAction:
+		return g.sharesBuilding(playerId, a.Payload.BuildingId)
+	case game.UnitDequeuedAction:
+		return g.sharesBuilding(playerId, a.Payload.BuildingId)
+	case game.ProductionProgressAction:
+		return g.sharesBuilding(playerId, a.Payload.BuildingId)
+	case game.RallySetAction:
+		return g.sharesBuilding(playerId, a.Payload.BuildingId)
+	}
+	return true
+}
+
+// sharesBuilding tells whether player sees production of building, it is owned by the player or an ally
+func (g *serverGame) sharesBuilding(playerId game.PlayerIdType, buildingId game.BuildingIdType) bool {
+	b := g.store.GetBuildingById(buildingId)
+	return b != nil && game.SharesVision(g.store, b.Owner, playerId)
+}
+
+// UpdateVision returns actions of units which entered or left vision of player since the last update
+func (g *serverGame) UpdateVision(playerId game.PlayerIdType) []game.Action {
+	known := g.knownBy(playerId)
+	visible := game.VisibleTiles(g.store, playerId)
+	actions := make([]game.Action, 0)
+	seen := make(map[game.UnitIdType]bool, len(known))
+	for _, u := range g.store.GetAllUnits() {
+		if !game.SeesUnit(g.store, visible, playerId, u) {
+			continue
+		}
+		seen[u.Id] = true
+		if known[u.Id] {
+			continue
+		}
+		known[u.Id] = true
+		actions = append(actions, game.UnitEnteredVisionAction{
+			Type: game.UnitEnteredVisionActionType,
+			Payload: game.UnitEnteredVisionPayload{
+				PlayerId: playerId,
+				Unit:     *u,
+			},
+		})
+	}
+	for id := range known {
+		if seen[id] {
+			continue
+		}
+		delete(known, id)
+		if g.store.GetUnitById(id) == nil {
+			// died, player already got it
+			continue
+		}
+		actions = append(actions, game.UnitLeftVisionAction{
+			Type: game.UnitLeftVisionActionType,
+			Payload: game.UnitLeftVisionPayload{
+				PlayerId: playerId,
+				UnitId:   id,
+			},
+		})
+	}
+	return append(actions, g.updateBuildingVision(playerId, visible)...)
+}
+
+// updateBuildingVision returns current state of buildings which came into sight and destruction of known buildings
+// whose place is seen again
+func (g *serverGame) updateBuildingVision(playerId game.PlayerIdType, visible map[image.Point]bool) []game.Action {
+	known := g.buildingsKnownBy(playerId)
+	actions := make([]game.Action, 0)
+	for _, b := range g.store.GetAllBuildings() {
+		sees := game.SeesBuilding(g.store, visible, playerId, b)
+		if sees && !known[b.Id].visible {
+			actions = append(actions, game.BuildingPlacedAction{
+				Type:    game.BuildingPlacedActionType,
+				Payload: *b,
+			})
+		}
+		if sees || known[b.Id].visible {
+			known[b.Id] = knownBuilding{visible: sees, area: b.Rect()}
+		}
+	}
+	for id, kb := range known {
+		if g.store.GetBuildingById(id) != nil || !game.SeesArea(visible, kb.area) {
+			continue
+		}
+		delete(known, id)
+		actions = append(actions, game.BuildingDestroyedAction{
+			Type:    game.BuildingDestroyedActionType,
+			Payload: game.BuildingDestroyedPayload{BuildingId: id},
+		})
+	}
+	return actions
+}
+
+// visibleUnits returns units seen by player, they become known to the player
+func (g *serverGame) visibleUnits(playerId game.PlayerIdType) []game.Unit {
+	known := make(map[game.UnitIdType]bool)
+	g.known[playerId] = known
+	visible := game.VisibleTiles(g.store, playerId)
+	units := make([]game.Unit, 0)
+	for _, u := range g.store.GetAllUnits() {
+		if g.mode == game.LockstepMode || game.SeesUnit(g.store, visible, playerId, u) {
+			known[u.Id] = true
+			units = append(units, *u)
+		}
+	}
+	return units
+}
+
+// visibleBuildings returns buildings seen by player, they become known to the player
+func (g *serverGame) visibleBuildings(playerId game.PlayerIdType) []game.Building {
+	known := make(map[game.BuildingIdType]knownBuilding)
+	g.knownBuildings[playerId] = known
+	visible := game.VisibleTiles(g.store, playerId)
+	buildings := make([]game.Building, 0)
+	for _, b := range g.store.GetAllBuildings() {
+		if g.mode == game.LockstepMode || game.SeesBuilding(g.store, visible, playerId, b) {
+			known[b.Id] = knownBuilding{visible: true, area: b.Rect()}
+			buildings = append(buildings, *b)
+		}
+	}
+	return buildings
+}
+
+// visibleStockpiles returns stockpiles of player and allies, lockstep clients get all of them
+func (g *serverGame) visibleStockpiles(playerId game.PlayerIdType) []game.PlayerResourcesPayload {
+	stockpiles := make([]game.PlayerResourcesPayload, 0)
+	for _, p := range g.store.GetAllPlayers() {
+		if g.mode == game.LockstepMode || game.SharesVision(g.store, p.Id, playerId) {
+			stockpiles = append(stockpiles, game.PlayerResourcesPayload{
+				PlayerId:  p.Id,
+				Resources: g.store.GetResources(p.Id),
+			})
+		}
+	}
+	return stockpiles
+}
+
+// ForgetPlayer drops units and buildings known to player who disconnected
+func (g *serverGame) ForgetPlayer(playerId game.PlayerIdType) {
+	delete(g.known, playerId)
+	delete(g.knownBuildings, playerId)
+}
+
+func (g *serverGame) knownBy(playerId game.PlayerIdType) map[game.UnitIdType]bool {
+	known, ok := g.known[playerId]
+	if !ok {
+		known = make(map[game.UnitIdType]bool)
+		g.known[playerId] = known
+	}
+	return known
+}
+
+func (g *serverGame) buildingsKnownBy(playerId game.PlayerIdType) map[game.BuildingIdType]knownBuilding {
+	known, ok := g.knownBuildings[playerId]
+	if !ok {
+		known = make(map[game.BuildingIdType]knownBuilding)
+		g.knownBuildings[playerId] = known
+	}
+	return known
+}
@@ -1,32 +1,60 @@
 package main
 
 import (
+	"errors"
+	"fmt"
 	"image"
 	"log"
+	"sync"
 
 	"github.com/bmcszk/gptrts/pkg/game"
 	"github.com/bmcszk/gptrts/pkg/world"
+	"github.com/google/uuid"
+)
+
+const (
+	checksumHistory = 10  // number of checksums kept for comparison with late clients
+	maxMapLoadSize  = 256 // max width and height of map area requested by client
 )
 
 type serverGame struct {
 	*game.GameLogic
-	store        game.Store
-	worldService world.WorldService
-	starting     map[image.Point]*game.PlayerIdType // starting point for each player, very temporary solution
+	store          game.Store
+	worldService   world.WorldService
+	lobby          game.Lobby // slot assignment of players, match starts when all are ready
+	mode           game.NetworkMode
+	checksums      map[int]uint64 // recent state checksums by tick, lockstep mode only
+	defs           *game.Definitions
+	keyBuilt       map[game.PlayerIdType]bool                                  // players who completed key building, see building victory condition
+	known          map[game.PlayerIdType]map[game.UnitIdType]bool              // units sent to clients of player, see Observes
+	knownBuildings map[game.PlayerIdType]map[game.BuildingIdType]knownBuilding // buildings sent to clients of player, see Observes
+	reserved       reservations                                                // lockstep mode only, see reservations
+	loaded         []loadedMap                                                 // map areas loaded by world service, see applyLoadedMaps
+	loadedMux      *sync.Mutex
+}
+
+// loadedMap is response of world service waiting for the tick, dispatch routes it to client which requested it
+type loadedMap struct {
+	resp     *world.WorldResponse
+	playerId game.PlayerIdType
+	dispatch game.DispatchFunc
 }
 
-func newServerGame(store game.Store, worldService world.WorldService) *serverGame {
+func newServerGame(store game.Store, worldService world.WorldService, mode game.NetworkMode, defs *game.Definitions, mapCfg *mapConfig) *serverGame {
 	g := &serverGame{
-		store:        store,
-		GameLogic:    game.NewGameLogic(store),
-		worldService: worldService,
-		starting:     make(map[image.Point]*game.PlayerIdType),
+		store:          store,
+		GameLogic:      game.NewGameLogic(store),
+		worldService:   worldService,
+		lobby:          game.NewLobby(mapCfg.slots()),
+		mode:           mode,
+		checksums:      make(map[int]uint64),
+		defs:           defs,
+		keyBuilt:       make(map[game.PlayerIdType]bool),
+		known:          make(map[game.PlayerIdType]map[game.UnitIdType]bool),
+		knownBuildings: make(map[game.PlayerIdType]map[game.BuildingIdType]knownBuilding),
+		reserved:       newReservations(),
+		loadedMux:      &sync.Mutex{},
 	}
-	g.starting[image.Pt(1, 1)] = nil
-	g.starting[image.Pt(15, 1)] = nil
-	g.starting[image.Pt(1, 15)] = nil
-	g.starting[image.Pt(15, 15)] = nil
-
 	return g
 }
 
@@ -36,74 +64,561 @@ func (g *serverGame) HandleAction(action game.Action, dispatch game.DispatchFunc
 	switch a := action.(type) {
 	case game.PlayerJoinAction:
 		g.handlePlayerJoinAction(a, dispatch)
+	case game.SpectatorJoinAction:
+		g.handleSpectatorJoinAction(a, dispatch)
+	case game.MoveStartAction:
+		g.handleMoveStartAction(a, dispatch)
+	case game.AttackAction:
+		g.handleAttackAction(a, dispatch)
+	case game.GatherStartAction:
+		g.handleGatherStartAction(a, dispatch)
+	case game.PlaceBuildingAction:
+		g.handlePlaceBuildingAction(a, dispatch)
+	case game.QueueUnitAction:
+		g.handleQueueUnitAction(a, dispatch)
+	case game.QueueCancelAction:
+		g.handleQueueCancelAction(a, dispatch)
+	case game.SetRallyAction:
+		g.handleSetRallyAction(a, dispatch)
+	case game.ResourceDeliveredAction:
+		g.handleResourceDeliveredAction(a, dispatch)
 	case game.MapLoadAction:
 		g.handleMapLoadAction(a, dispatch)
+	case game.TickAction:
+		g.handleTickAction(a)
+	case game.StateChecksumAction:
+		g.handleStateChecksumAction(a, dispatch)
+	case game.GameOverAction:
+		g.handleGameOverAction(a)
+	case game.SelectSlotAction:
+		g.handleSelectSlotAction(a, dispatch)
+	case game.SelectColorAction:
+		g.handleSelectColorAction(a, dispatch)
+	case game.SelectTeamAction:
+		g.handleSelectTeamAction(a, dispatch)
+	case game.ReadyAction:
+		g.handleReadyAction(a, dispatch)
+	case game.SetDiplomacyAction:
+		g.handleSetDiplomacyAction(a, dispatch)
+	}
+}
+
+// ValidateAction checks if action sent by player is an intent the player is allowed to issue
+func (g *serverGame) ValidateAction(playerId game.PlayerIdType, action game.Action) error {
+	switch a := action.(type) {
+	case game.PlayerJoinAction, game.SpectatorJoinAction:
+		return nil
+	case game.MapLoadAction:
+		return validateMapLoad(a.Payload.WorldRequest)
+	case game.SelectSlotAction, game.SelectColorAction, game.SelectTeamAction, game.ReadyAction:
+		return g.validateLobbyAction(playerId, action)
+	}
+	if !g.lobby.IsPlaying() {
+		return errors.New("match not started")
+	}
+	if g.Result() != nil {
+		return errors.New("game over")
+	}
+	if player, ok := g.store.GetPlayer(playerId); ok && player.Defeated {
+		return errors.New("player defeated")
+	}
+	switch a := action.(type) {
+	case game.MoveStartAction:
+		if _, err := g.ownedUnit(playerId, a.Payload.UnitId); err != nil {
+			return err
+		}
+		return g.checkTarget(a.Payload.Point)
+	case game.AttackAction:
+		if _, err := g.ownedUnit(playerId, a.Payload.UnitId); err != nil {
+			return err
+		}
+		if a.Payload.BuildingId != game.ZeroBuildingId {
+			target := g.store.GetBuildingById(a.Payload.BuildingId)
+			if target == nil {
+				return errors.New("target not found")
+			}
+			if !game.CanAttack(g.store, playerId, target.Owner) {
+				return errors.New("target not enemy")
+			}
+			return nil
+		}
+		target := g.store.GetUnitById(a.Payload.TargetId)
+		if target == nil {
+			return errors.New("target not found")
+		}
+		if !game.CanAttack(g.store, playerId, target.Owner) {
+			return errors.New("target not enemy")
+		}
+		return nil
+	case game.GatherStartAction:
+		unit, err := g.ownedUnit(playerId, a.Payload.UnitId)
+		if err != nil {
+			return err
+		}
+		if !unit.HasAbility(game.GatherAbility) {
+			return errors.New("unit cannot gather")
+		}
+		t, ok := g.store.GetTile(a.Payload.Point)
+		if !ok || t.Resource == nil || t.Resource.Amount == 0 {
+			return errors.New("no resource")
+		}
+		return nil
+	case game.PlaceBuildingAction:
+		if a.Payload.PlayerId != playerId {
+			return errors.New("player mismatch")
+		}
+		buildingType, ok := g.defs.BuildingType(a.Payload.Type)
+		if !ok {
+			return errors.New("building type not found")
+		}
+		if !g.resources(playerId).Covers(buildingType.Cost) {
+			return errors.New("not enough resources")
+		}
+		return g.checkFootprint(a.Payload.Point, image.Pt(buildingType.Width, buildingType.Height))
+	case game.QueueUnitAction:
+		building, err := g.ownedBuilding(playerId, a.Payload.BuildingId)
+		if err != nil {
+			return err
+		}
+		if !building.IsComplete() {
+			return errors.New("building not complete")
+		}
+		if !building.CanProduce(a.Payload.UnitType) {
+			return errors.New("unit type not produced by building")
+		}
+		if len(building.Queue)+g.reserved.queued[building.Id] >= building.QueueLimit {
+			return errors.New("queue full")
+		}
+		unitType, _ := g.defs.UnitType(a.Payload.UnitType)
+		if !g.resources(playerId).Covers(unitType.Cost) {
+			return errors.New("not enough resources")
+		}
+		return nil
+	case game.QueueCancelAction:
+		building, err := g.ownedBuilding(playerId, a.Payload.BuildingId)
+		if err != nil {
+			return err
+		}
+		if building.QueueIndex(a.Payload.UnitId) < 0 || g.reserved.canceled[a.Payload.UnitId] {
+			return errors.New("unit not queued")
+		}
+		return nil
+	case game.SetRallyAction:
+		building, err := g.ownedBuilding(playerId, a.Payload.BuildingId)
+		if err != nil {
+			return err
+		}
+		if a.Payload.Point.In(building.Rect()) {
+			return errors.New("rally point inside building")
+		}
+		return g.checkTarget(a.Payload.Point)
+	case game.SetDiplomacyAction:
+		if a.Payload.PlayerId != playerId {
+			return errors.New("player mismatch")
+		}
+		if a.Payload.TargetId == playerId {
+			return errors.New("diplomacy with oneself")
+		}
+		if _, ok := g.store.GetPlayer(a.Payload.TargetId); !ok {
+			return errors.New("player not found")
+		}
+		switch a.Payload.Stance {
+		case game.AllyStance, game.NeutralStance, game.EnemyStance:
+			return nil
+		default:
+			return errors.New("stance unknown")
+		}
+	case game.StateChecksumAction:
+		if g.mode != game.LockstepMode {
+			return errors.New("action not permitted")
+		}
+		if a.Payload.PlayerId != playerId {
+			return errors.New("player mismatch")
+		}
+		return nil
+	default:
+		return errors.New("action not permitted")
+	}
+}
+
+func (g *serverGame) ownedUnit(playerId game.PlayerIdType, unitId game.UnitIdType) (*game.Unit, error) {
+	unit := g.store.GetUnitById(unitId)
+	if unit == nil {
+		return nil, errors.New("unit not found")
+	}
+	if unit.Owner != playerId {
+		return nil, errors.New("unit not owned")
 	}
+	return unit, nil
+}
+
+func (g *serverGame) ownedBuilding(playerId game.PlayerIdType, buildingId game.BuildingIdType) (*game.Building, error) {
+	building := g.store.GetBuildingById(buildingId)
+	if building == nil {
+		return nil, errors.New("building not found")
+	}
+	if building.Owner != playerId {
+		return nil, errors.New("building not owned")
+	}
+	return building, nil
+}
+
+// checkTarget checks if target point is on the map, the area must have been loaded
+func (g *serverGame) checkTarget(p image.Point) error {
+	if t, ok := g.store.GetTile(p); !ok || !t.Loaded {
+		return errors.New("target out of bounds")
+	}
+	return nil
+}
+
+// validateMapLoad limits area of map request, so one request cannot make server generate huge world
+func validateMapLoad(r world.WorldRequest) error {
+	if r.MaxX < r.MinX || r.MaxY < r.MinY {
+		return errors.New("map area empty")
+	}
+	if r.MaxX-r.MinX >= maxMapLoadSize || r.MaxY-r.MinY >= maxMapLoadSize {
+		return errors.New("map area too large")
+	}
+	return nil
+}
+
+// checkFootprint checks if building can be placed on tiles, map of the area must be loaded
+func (g *serverGame) checkFootprint(position, footprint image.Point) error {
+	for _, p := range game.FootprintPoints(position, footprint) {
+		t, ok := g.store.GetTile(p)
+		if !ok || !t.Loaded {
+			return errors.New("terrain unknown")
+		}
+		if !game.TerrainOf(t).Passable {
+			return errors.New("terrain not passable")
+		}
+		if t.Unit != nil || t.Building != nil || g.footprintReserved(p) {
+			return errors.New("place occupied")
+		}
+		if t.Resource != nil && t.Resource.Amount > 0 {
+			return errors.New("place occupied by resource")
+		}
+	}
+	return nil
 }
 
 func (g *serverGame) handlePlayerJoinAction(action game.PlayerJoinAction, dispatch game.DispatchFunc) {
 	player := action.Payload
 	id := player.Id
-	_, existing := g.store.GetPlayer(id)
+	existingPlayer, existing := g.store.GetPlayer(id)
+	if reason := g.joinRejectReason(id, existing); reason != "" {
+		log.Printf("player %s join rejected: %s", uuid.UUID(id), reason)
+		dispatch(game.JoinRejectedAction{
+			Type: game.JoinRejectedActionType,
+			Payload: game.JoinRejectedPayload{
+				PlayerId: id,
+				Reason:   reason,
+			},
+		})
+		return
+	}
+	if existing {
+		player.Start = existingPlayer.Start
+		player.Color = existingPlayer.Color
+		player.Team = existingPlayer.Team
+		player.Relations = existingPlayer.Relations
+		player.Defeated = existingPlayer.Defeated
+	}
 	g.store.StorePlayer(player)
 
+	// player resuming session mid-match replaces its state with full snapshot
+	if existing && g.lobby.IsPlaying() {
+		dispatch(g.newResyncAction(player.Id))
+		return
+	}
+
+	successAction := g.newJoinSuccessAction(player.Id, false)
+	dispatch(successAction)
+
+	// units are spawned when the match starts, players joining later only watch
+	if g.lobby.IsPlaying() {
+		return
+	}
+	if g.lobby.SlotOf(player.Id) < 0 {
+		g.takeSlot(player)
+	}
+	dispatch(g.newLobbyStateAction())
+}
+
+func (g *serverGame) handleSpectatorJoinAction(action game.SpectatorJoinAction, dispatch game.DispatchFunc) {
+	log.Printf("spectator %s %q joined", uuid.UUID(action.Payload.Id), action.Payload.Name)
+	dispatch(g.newJoinSuccessAction(action.Payload.Id, true))
+}
+
+// isParticipant tells whether player has joined the match, its clients may only watch through its vision
+func (g *serverGame) isParticipant(playerId game.PlayerIdType) bool {
+	_, ok := g.store.GetPlayer(playerId)
+	return ok
+}
+
+// newJoinSuccessAction returns snapshot of the match, player gets what it sees, spectator gets everything
+func (g *serverGame) newJoinSuccessAction(playerId game.PlayerIdType, spectator bool) game.PlayerJoinSuccessAction {
 	successAction := game.PlayerJoinSuccessAction{
 		Type: game.PlayerJoinSuccessActionType,
 		Payload: game.PlayerJoinSuccessPayload{
-			PlayerId: player.Id,
-			Units:    make([]game.Unit, 0),
-			Players:  make([]game.Player, 0),
+			PlayerId:    playerId,
+			Players:     make([]game.Player, 0),
+			Mode:        g.mode,
+			Tick:        g.Tick(),
+			Definitions: g.defs,
+			Result:      g.Result(),
+			Lobby:       g.newLobbyStateAction().Payload,
+			Spectator:   spectator,
 		},
 	}
-	for _, unit := range g.store.GetAllUnits() {
-		successAction.Payload.Units = append(successAction.Payload.Units, *unit)
-	}
 	for _, player := range g.store.GetAllPlayers() {
 		successAction.Payload.Players = append(successAction.Payload.Players, *player)
 	}
-	dispatch(successAction)
+	if !spectator {
+		successAction.Payload.Units = g.visibleUnits(playerId)
+		successAction.Payload.Buildings = g.visibleBuildings(playerId)
+		successAction.Payload.Stockpiles = g.visibleStockpiles(playerId)
+		return successAction
+	}
+	s := g.Snapshot()
+	successAction.Payload.Units = s.Units
+	successAction.Payload.Buildings = s.Buildings
+	successAction.Payload.Stockpiles = s.Stockpiles
+	return successAction
+}
 
-	// unit spawn only for new player
-	if existing {
-		return
+// newResyncAction returns snapshot of the match, in authoritative mode units, buildings, stockpiles and tiles
+// are limited to player vision
+func (g *serverGame) newResyncAction(playerId game.PlayerIdType) game.ResyncAction {
+	s := g.Snapshot()
+	units := g.visibleUnits(playerId)
+	buildings := g.visibleBuildings(playerId)
+	stockpiles := g.visibleStockpiles(playerId)
+	if g.mode != game.LockstepMode {
+		s.Units = units
+		s.Buildings = buildings
+		s.Stockpiles = stockpiles
+		visible := game.VisibleTiles(g.store, playerId)
+		s.Tiles = make([]world.Tile, 0, len(visible))
+		s.ResourceNodes = make([]game.ResourceNode, 0)
+		for p := range visible {
+			t, ok := g.store.GetTile(p)
+			if !ok || !t.Loaded {
+				continue
+			}
+			s.Tiles = append(s.Tiles, *t.Tile)
+			if t.Resource != nil {
+				s.ResourceNodes = append(s.ResourceNodes, *t.Resource)
+			}
+		}
 	}
+	return game.ResyncAction{
+		Type: game.ResyncActionType,
+		Payload: game.ResyncPayload{
+			PlayerId:    playerId,
+			Snapshot:    s,
+			Mode:        g.mode,
+			Definitions: g.defs,
+			Lobby:       g.newLobbyStateAction().Payload,
+		},
+	}
+}
 
-	var startingP image.Point
-	for sp, p := range g.starting {
-		if p == nil {
-			startingP = sp
-			g.starting[sp] = &player.Id
-			break
+// joinRejectReason tells why player cannot join, empty when player can join
+func (g *serverGame) joinRejectReason(playerId game.PlayerIdType, existing bool) string {
+	if g.lobby.IsPlaying() {
+		if !existing {
+			return "match already started"
 		}
+		return ""
 	}
-	unit := game.NewUnit(action.Payload.Id, player.Color, game.ToPF(startingP), 16, 16)
-	unitAction := game.SpawnUnitAction{
-		Type:    game.SpawnUnitActionType,
-		Payload: *unit,
+	if g.lobby.SlotOf(playerId) < 0 && g.lobby.FreeSlot() < 0 {
+		return fmt.Sprintf("map is full, %d players max", len(g.lobby.Slots))
 	}
-	dispatch(unitAction)
+	return ""
 }
 
-func (g *serverGame) handleMapLoadAction(action game.MapLoadAction, dispatch game.DispatchFunc) {
+func (g *serverGame) spawnStartingArmy(player game.Player, startingP image.Point, dispatch game.DispatchFunc) {
+	// spawns may be queued for the next tick, so points are reserved here
+	taken := make(map[image.Point]bool)
+	for _, typeId := range g.defs.StartingArmy {
+		unitType, _ := g.defs.UnitType(typeId)
+		p, ok := game.FindFreePoint(g.store, startingP, taken)
+		if !ok {
+			log.Printf("no space to spawn %s for player %s", typeId, uuid.UUID(player.Id))
+			return
+		}
+		taken[p] = true
+		unit := game.NewUnitOfType(unitType, player.Id, player.Color, game.ToPF(p))
+		unitAction := game.SpawnUnitAction{
+			Type:    game.SpawnUnitActionType,
+			Payload: *unit,
+		}
+		dispatch(unitAction)
+	}
+}
 
-	_, ok1 := g.store.GetTile(image.Pt(action.Payload.MinX, action.Payload.MinY))
-	_, ok2 := g.store.GetTile(image.Pt(action.Payload.MaxX, action.Payload.MaxY))
-	if ok1 && ok2 {
-		tiles := make([]world.Tile, 0)
-		for x := action.Payload.MinX; x <= action.Payload.MaxX; x++ {
-			for y := action.Payload.MinY; y <= action.Payload.MaxY; y++ {
-				t, ok := g.store.GetTile(image.Pt(x, y))
-				if !ok {
-					continue
-				}
-				tiles = append(tiles, *t.Tile)
-			}
+// handleTickAction remembers checksums of recent lockstep ticks
+func (g *serverGame) handleTickAction(action game.TickAction) {
+	tick := action.Payload.Tick
+	if tick%game.ChecksumInterval != 0 {
+		return
+	}
+	g.checksums[tick] = g.Checksum()
+	delete(g.checksums, tick-checksumHistory*game.ChecksumInterval)
+}
+
+func (g *serverGame) handleStateChecksumAction(action game.StateChecksumAction, dispatch game.DispatchFunc) {
+	expected, ok := g.checksums[action.Payload.Tick]
+	if !ok || expected == action.Payload.Checksum {
+		return
+	}
+	log.Printf("player %s desync at tick %d", uuid.UUID(action.Payload.PlayerId), action.Payload.Tick)
+	dispatch(game.DesyncAction{
+		Type: game.DesyncActionType,
+		Payload: game.DesyncPayload{
+			PlayerId: action.Payload.PlayerId,
+			Tick:     action.Payload.Tick,
+			Expected: expected,
+			Actual:   action.Payload.Checksum,
+		},
+	})
+}
+
+func (g *serverGame) handlePlaceBuildingAction(action game.PlaceBuildingAction, dispatch game.DispatchFunc) {
+	player, ok := g.store.GetPlayer(action.Payload.PlayerId)
+	if !ok {
+		return
+	}
+	buildingType, _ := g.defs.BuildingType(action.Payload.Type)
+	g.setResources(player.Id, g.resources(player.Id).Sub(buildingType.Cost), dispatch)
+	g.reserveFootprint(action.Payload.Point, image.Pt(buildingType.Width, buildingType.Height))
+	building := game.NewBuildingOfType(buildingType, player.Id, player.Color, action.Payload.Point)
+	dispatch(game.BuildingPlacedAction{
+		Type:    game.BuildingPlacedActionType,
+		Payload: *building,
+	})
+}
+
+// handleQueueUnitAction deducts cost and queues unit prepared from its type
+func (g *serverGame) handleQueueUnitAction(action game.QueueUnitAction, dispatch game.DispatchFunc) {
+	building := g.store.GetBuildingById(action.Payload.BuildingId)
+	if building == nil {
+		return
+	}
+	unitType, _ := g.defs.UnitType(action.Payload.UnitType)
+	g.setResources(building.Owner, g.resources(building.Owner).Sub(unitType.Cost), dispatch)
+	g.reserveQueued(building.Id)
+	dispatch(game.UnitQueuedAction{
+		Type: game.UnitQueuedActionType,
+		Payload: game.UnitQueuedPayload{
+			BuildingId: building.Id,
+			Item:       game.NewQueueItem(unitType, building),
+		},
+	})
+}
+
+// handleQueueCancelAction removes unit from queue and refunds its cost
+func (g *serverGame) handleQueueCancelAction(action game.QueueCancelAction, dispatch game.DispatchFunc) {
+	building := g.store.GetBuildingById(action.Payload.BuildingId)
+	if building == nil {
+		return
+	}
+	i := building.QueueIndex(action.Payload.UnitId)
+	if i < 0 {
+		return
+	}
+	g.setResources(building.Owner, g.resources(building.Owner).Add(building.Queue[i].Cost), dispatch)
+	g.reserveCanceled(action.Payload.UnitId)
+	dispatch(game.UnitDequeuedAction{
+		Type: game.UnitDequeuedActionType,
+		Payload: game.UnitDequeuedPayload{
+			BuildingId: building.Id,
+			UnitId:     action.Payload.UnitId,
+		},
+	})
+}
+
+// UpdateProduction spawns finished units next to rally points of their buildings, the closest free tile is chosen
+// here, so every game logic spawns the unit at the same place
+func (g *serverGame) UpdateProduction(dispatch game.DispatchFunc) {
+	taken := make(map[image.Point]bool)
+	for _, b := range g.store.GetAllBuildings() {
+		if !b.IsAlive() || !b.IsComplete() || len(b.Queue) == 0 {
+			continue
+		}
+		item := b.Queue[0]
+		if item.Progress < item.Time || g.reserved.canceled[item.Unit.Id] {
+			continue
+		}
+		p, ok := game.FindFreePoint(g.store, b.Rally, taken)
+		if !ok {
+			// wait until there is space
+			continue
 		}
+		taken[p] = true
+		unit := item.Unit
+		unit.Position = game.ToPF(p)
+		g.reserveCanceled(unit.Id)
+		dispatch(game.UnitDequeuedAction{
+			Type: game.UnitDequeuedActionType,
+			Payload: game.UnitDequeuedPayload{
+				BuildingId: b.Id,
+				UnitId:     unit.Id,
+			},
+		})
+		dispatch(game.SpawnUnitAction{
+			Type:    game.SpawnUnitActionType,
+			Payload: unit,
+		})
+	}
+}
+
+func (g *serverGame) handleSetRallyAction(action game.SetRallyAction, dispatch game.DispatchFunc) {
+	dispatch(game.RallySetAction{
+		Type: game.RallySetActionType,
+		Payload: game.RallySetPayload{
+			BuildingId: action.Payload.BuildingId,
+			Point:      action.Payload.Point,
+		},
+	})
+}
+
+func (g *serverGame) handleSetDiplomacyAction(action game.SetDiplomacyAction, dispatch game.DispatchFunc) {
+	dispatch(game.DiplomacyChangedAction{
+		Type: game.DiplomacyChangedActionType,
+		Payload: game.DiplomacyChangedPayload{
+			PlayerId: action.Payload.PlayerId,
+			TargetId: action.Payload.TargetId,
+			Stance:   action.Payload.Stance,
+		},
+	})
+}
+
+// handleResourceDeliveredAction publishes authoritative stockpile after delivery
+func (g *serverGame) handleResourceDeliveredAction(action game.ResourceDeliveredAction, dispatch game.DispatchFunc) {
+	playerId := action.Payload.PlayerId
+	dispatch(newPlayerResourcesUpdatedAction(playerId, g.store.GetResources(playerId)))
+}
+
+func newPlayerResourcesUpdatedAction(playerId game.PlayerIdType, resources game.Resources) game.PlayerResourcesUpdatedAction {
+	return game.PlayerResourcesUpdatedAction{
+		Type: game.PlayerResourcesUpdatedActionType,
+		Payload: game.PlayerResourcesPayload{
+			PlayerId:  playerId,
+			Resources: resources,
+		},
+	}
+}
+
+func (g *serverGame) handleMapLoadAction(action game.MapLoadAction, dispatch game.DispatchFunc) {
+	if tiles, ok := g.loadedTiles(action.Payload.WorldRequest); ok {
 		successAction := game.MapLoadSuccessAction{
 			Type: game.MapLoadSuccessActionType,
 			Payload: game.MapLoadSuccessPayload{
 				WorldResponse: world.WorldResponse{Tiles: tiles},
 				PlayerId:      action.Payload.PlayerId,
+				ResourceNodes: g.resourceNodes(tiles),
 			},
 		}
 		dispatch(successAction)
@@ -111,19 +626,61 @@ func (g *serverGame) handleMapLoadAction(action game.MapLoadAction, dispatch gam
 
 	}
 
-	resp, err := g.worldService.Load(action.Payload.WorldRequest)
-	if err != nil {
-		log.Printf("error loading map: %s", err)
-		return
-		// TODO: error handling
-		// TODO: send error to client
-	}
-	successAction := game.MapLoadSuccessAction{
-		Type: game.MapLoadSuccessActionType,
-		Payload: game.MapLoadSuccessPayload{
-			WorldResponse: *resp,
-			PlayerId:      action.Payload.PlayerId,
-		},
+	// generating the world may take long, it runs without blocking the room and result is applied on next tick
+	go func() {
+		resp, err := g.worldService.Load(action.Payload.WorldRequest)
+		if err != nil {
+			log.Printf("error loading map: %s", err)
+			return
+			// TODO: error handling
+			// TODO: send error to client
+		}
+		g.loadedMux.Lock()
+		defer g.loadedMux.Unlock()
+		g.loaded = append(g.loaded, loadedMap{resp: resp, playerId: action.Payload.PlayerId, dispatch: dispatch})
+	}()
+}
+
+// loadedTiles returns tiles of the area when all of them were loaded from world service before
+func (g *serverGame) loadedTiles(r world.WorldRequest) ([]world.Tile, bool) {
+	tiles := make([]world.Tile, 0)
+	for x := r.MinX; x <= r.MaxX; x++ {
+		for y := r.MinY; y <= r.MaxY; y++ {
+			t, ok := g.store.GetTile(image.Pt(x, y))
+			if !ok || !t.Loaded {
+				return nil, false
+			}
+			tiles = append(tiles, *t.Tile)
+		}
 	}
-	dispatch(successAction)
+	return tiles, true
+}
+
+// applyLoadedMaps dispatches map areas loaded since the last tick
+func (g *serverGame) applyLoadedMaps() {
+	g.loadedMux.Lock()
+	loaded := g.loaded
+	g.loaded = nil
+	g.loadedMux.Unlock()
+	for _, l := range loaded {
+		l.dispatch(game.MapLoadSuccessAction{
+			Type: game.MapLoadSuccessActionType,
+			Payload: game.MapLoadSuccessPayload{
+				WorldResponse: *l.resp,
+				PlayerId:      l.playerId,
+				ResourceNodes: g.resourceNodes(l.resp.Tiles),
+			},
+		})
+	}
+}
+
+// resourceNodes returns current state of resource nodes known on tiles
+func (g *serverGame) resourceNodes(tiles []world.Tile) []game.ResourceNode {
+	nodes := make([]game.ResourceNode, 0)
+	for _, wt := range tiles {
+		if t, ok := g.store.GetTile(wt.Point); ok && t.Resource != nil {
+			nodes = append(nodes, *t.Resource)
+		}
+	}
+	return nodes
 }
@@ -0,0 +1,215 @@
+package main
+
+import (
+	"errors"
+	"log"
+
+	"github.com/bmcszk/gptrts/pkg/game"
+	"github.com/google/uuid"
+)
+
+const lobbyCountdown = 5 * game.TickRate // ticks from all players ready to start of the match
+
+// UpdateLobby counts down to start of the match
+func (g *serverGame) UpdateLobby(dispatch game.DispatchFunc) {
+	if g.lobby.Phase != game.LobbyPhaseCountdown {
+		return
+	}
+	g.lobby.Countdown--
+	if g.lobby.Countdown > 0 {
+		if g.lobby.Countdown%game.TickRate == 0 {
+			dispatch(g.newLobbyStateAction())
+		}
+		return
+	}
+	g.startMatch(dispatch)
+}
+
+// startMatch gives players of all slots their starting resources and army
+func (g *serverGame) startMatch(dispatch game.DispatchFunc) {
+	g.lobby.Phase = game.LobbyPhasePlaying
+	dispatch(g.newLobbyStateAction())
+	for _, slot := range g.lobby.Slots {
+		if slot.IsFree() {
+			continue
+		}
+		player, ok := g.store.GetPlayer(slot.PlayerId)
+		if !ok {
+			continue
+		}
+		g.store.StoreResources(player.Id, g.defs.StartingResources)
+		dispatch(newPlayerResourcesUpdatedAction(player.Id, g.defs.StartingResources))
+		g.spawnStartingArmy(*player, slot.Start, dispatch)
+	}
+	log.Printf("match started")
+}
+
+// takeSlot puts new player to first free slot, color of the player is kept if it is set and nobody uses it
+func (g *serverGame) takeSlot(player game.Player) {
+	i := g.lobby.FreeSlot()
+	if i < 0 {
+		log.Printf("no free slot for player %s", uuid.UUID(player.Id))
+		return
+	}
+	c := player.Color
+	if c.A == 0 || g.lobby.ColorTaken(c, player.Id) {
+		for _, pc := range game.PlayerColors {
+			if !g.lobby.ColorTaken(pc, player.Id) {
+				c = pc
+				break
+			}
+		}
+	}
+	slot := &g.lobby.Slots[i]
+	slot.PlayerId = player.Id
+	slot.Name = player.Name
+	slot.Color = c
+	slot.Ready = false
+	g.updatePlayer(*slot)
+	g.updatePhase()
+}
+
+// LeaveLobby frees slot of player who left before the match started
+func (g *serverGame) LeaveLobby(playerId game.PlayerIdType) bool {
+	if g.lobby.IsPlaying() {
+		return false
+	}
+	i := g.lobby.SlotOf(playerId)
+	if i < 0 {
+		return false
+	}
+	slot := &g.lobby.Slots[i]
+	slot.PlayerId = game.ZeroPlayerId
+	slot.Name = ""
+	slot.Ready = false
+	g.updatePhase()
+	return true
+}
+
+// updatePlayer copies lobby choices to the player
+func (g *serverGame) updatePlayer(slot game.Slot) {
+	player, ok := g.store.GetPlayer(slot.PlayerId)
+	if !ok {
+		return
+	}
+	player.Color = slot.Color
+	player.Team = slot.Team
+	player.Start = game.ToPF(slot.Start)
+}
+
+// updatePhase starts countdown when everybody is ready and stops it otherwise
+func (g *serverGame) updatePhase() {
+	switch {
+	case g.lobby.IsPlaying():
+	case g.lobby.AllReady():
+		if g.lobby.Phase != game.LobbyPhaseCountdown {
+			g.lobby.Phase = game.LobbyPhaseCountdown
+			g.lobby.Countdown = lobbyCountdown
+		}
+	default:
+		g.lobby.Phase = game.LobbyPhaseWaiting
+		g.lobby.Countdown = 0
+	}
+}
+
+func (g *serverGame) validateLobbyAction(playerId game.PlayerIdType, action game.Action) error {
+	if g.lobby.IsPlaying() {
+		return errors.New("match started")
+	}
+	i := g.lobby.SlotOf(playerId)
+	if i < 0 {
+		return errors.New("player has no slot")
+	}
+	slot := g.lobby.Slots[i]
+	switch a := action.(type) {
+	case game.SelectSlotAction:
+		if a.Payload.PlayerId != playerId {
+			return errors.New("player mismatch")
+		}
+		if a.Payload.Slot < 0 || a.Payload.Slot >= len(g.lobby.Slots) {
+			return errors.New("slot not found")
+		}
+		if !g.lobby.Slots[a.Payload.Slot].IsFree() {
+			return errors.New("slot taken")
+		}
+	case game.SelectColorAction:
+		if a.Payload.PlayerId != playerId {
+			return errors.New("player mismatch")
+		}
+		if g.lobby.ColorTaken(a.Payload.Color, playerId) {
+			return errors.New("color taken")
+		}
+	case game.SelectTeamAction:
+		if a.Payload.PlayerId != playerId {
+			return errors.New("player mismatch")
+		}
+		if a.Payload.Team < 1 || a.Payload.Team > len(g.lobby.Slots) {
+			return errors.New("team not found")
+		}
+	case game.ReadyAction:
+		if a.Payload.PlayerId != playerId {
+			return errors.New("player mismatch")
+		}
+		return nil
+	}
+	if slot.Ready {
+		return errors.New("player ready")
+	}
+	return nil
+}
+
+func (g *serverGame) handleSelectSlotAction(action game.SelectSlotAction, dispatch game.DispatchFunc) {
+	i := g.lobby.SlotOf(action.Payload.PlayerId)
+	if i < 0 {
+		return
+	}
+	slot := g.lobby.Slots[i]
+	target := &g.lobby.Slots[action.Payload.Slot]
+	// team and start stay with the slot
+	target.PlayerId = slot.PlayerId
+	target.Name = slot.Name
+	target.Color = slot.Color
+	g.lobby.Slots[i].PlayerId = game.ZeroPlayerId
+	g.lobby.Slots[i].Name = ""
+	g.updatePlayer(*target)
+	dispatch(g.newLobbyStateAction())
+}
+
+func (g *serverGame) handleSelectColorAction(action game.SelectColorAction, dispatch game.DispatchFunc) {
+	i := g.lobby.SlotOf(action.Payload.PlayerId)
+	if i < 0 {
+		return
+	}
+	g.lobby.Slots[i].Color = action.Payload.Color
+	g.updatePlayer(g.lobby.Slots[i])
+	dispatch(g.newLobbyStateAction())
+}
+
+func (g *serverGame) handleSelectTeamAction(action game.SelectTeamAction, dispatch game.DispatchFunc) {
+	i := g.lobby.SlotOf(action.Payload.PlayerId)
+	if i < 0 {
+		return
+	}
+	g.lobby.Slots[i].Team = action.Payload.Team
+	g.updatePlayer(g.lobby.Slots[i])
+	dispatch(g.newLobbyStateAction())
+}
+
+func (g *serverGame) handleReadyAction(action game.ReadyAction, dispatch game.DispatchFunc) {
+	i := g.lobby.SlotOf(action.Payload.PlayerId)
+	if i < 0 {
+		return
+	}
+	g.lobby.Slots[i].Ready = action.Payload.Ready
+	g.updatePhase()
+	dispatch(g.newLobbyStateAction())
+}
+
+func (g *serverGame) newLobbyStateAction() game.LobbyStateAction {
+	lobby := g.lobby
+	lobby.Slots = append([]game.Slot{}, g.lobby.Slots...)
+	return game.LobbyStateAction{
+		Type:    game.LobbyStateActionType,
+		Payload: lobby,
+	}
+}
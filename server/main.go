@@ -1,39 +1,120 @@
 package main
 
 import (
+	"flag"
 	"fmt"
 	"log"
 	"net/http"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+	"time"
 
 	"github.com/bmcszk/gptrts/pkg/comm"
 	"github.com/bmcszk/gptrts/pkg/game"
 	"github.com/bmcszk/gptrts/pkg/world"
+	"github.com/google/uuid"
 	"github.com/gorilla/websocket"
 )
 
+// defaultRoomId - room of clients which send PlayerJoinAction without choosing a room, it is never closed
+const defaultRoomId game.RoomIdType = "default"
+
 var upgrader = websocket.Upgrader{}
 
+// server manages rooms, every room runs separate match
 type server struct {
-	game    *serverGame
-	clients map[game.PlayerIdType]*comm.Client
+	rooms        map[game.RoomIdType]*room
+	mux          *sync.Mutex // guards rooms
+	mode         game.NetworkMode
+	defs         *game.Definitions
+	worldService world.WorldService
+	mapCfg       *mapConfig
+	admins       map[game.PlayerIdType]bool // players allowed to save matches, ids come from issued tokens
+	saveDir      string
+	replayDir    string // replays of matches are recorded when set
+	auth         *authenticator
+	rate         float64 // actions per second allowed for one connection, zero disables limit
+	burst        int
 }
 
-func newServer(g *serverGame) *server {
-	return &server{
-		game:    g,
-		clients: make(map[game.PlayerIdType]*comm.Client, 0), // connected clients,
+func newServer(mode game.NetworkMode, defs *game.Definitions, mapCfg *mapConfig, worldService world.WorldService, replayDir string) *server {
+	s := &server{
+		rooms:        make(map[game.RoomIdType]*room),
+		mux:          &sync.Mutex{},
+		mode:         mode,
+		defs:         defs,
+		worldService: worldService,
+		mapCfg:       mapCfg,
+		admins:       make(map[game.PlayerIdType]bool),
+		saveDir:      "saves",
+		replayDir:    replayDir,
+		rate:         20,
+		burst:        40,
 	}
+	s.createRoom(defaultRoomId, "Default")
+	return s
 }
 
 func main() {
-	s := newServer(newServerGame(game.NewStoreImpl(), world.NewWorldService()))
+	lockstep := flag.Bool("lockstep", false, "run deterministic lockstep mode instead of server authoritative simulation")
+	defsPath := flag.String("defs", "definitions.json", "game definitions file with unit types and starting army")
+	mapPath := flag.String("map", "map.json", "map file with start positions of players")
+	admins := flag.String("admins", "", "comma separated ids of players allowed to save matches, id is printed by client after authentication")
+	saveDir := flag.String("saves", "saves", "directory of saved matches")
+	loadPath := flag.String("load", "", "saved match file to resume in default room")
+	replayDir := flag.String("replays", "replays", "directory of recorded replays, empty disables recording")
+	secretPath := flag.String("secret", "secret.key", "key of session token signatures, generated when missing")
+	rate := flag.Float64("rate", 20, "actions per second allowed for one connection, 0 disables limit")
+	burst := flag.Int("burst", 40, "actions allowed in a burst above the rate")
+	flag.Parse()
+
+	defs, err := game.LoadDefinitions(*defsPath)
+	if err != nil {
+		log.Fatal(err)
+	}
+	mapCfg, err := loadMapConfig(*mapPath)
+	if err != nil {
+		log.Fatal(err)
+	}
+	worldService := world.NewWorldService()
+	go mapCfg.checkStarts(worldService)
+
+	mode := game.AuthoritativeMode
+	if *lockstep {
+		mode = game.LockstepMode
+	}
+	secret, err := loadSecret(*secretPath)
+	if err != nil {
+		log.Fatal(err)
+	}
+	s := newServer(mode, defs, mapCfg, worldService, *replayDir)
+	s.auth = &authenticator{secret: secret}
+	s.saveDir = *saveDir
+	s.rate, s.burst = *rate, *burst
+	for _, id := range strings.Split(*admins, ",") {
+		if id = strings.TrimSpace(id); id == "" {
+			continue
+		}
+		playerId, err := uuid.Parse(id)
+		if err != nil {
+			log.Fatalf("admin %q: %s", id, err)
+		}
+		s.admins[game.PlayerIdType(playerId)] = true
+	}
+	if *loadPath != "" {
+		if err := s.loadMatch(*loadPath); err != nil {
+			log.Fatal(err)
+		}
+	}
 
 	// Configure websocket route
 	http.HandleFunc("/ws", s.handleConnections)
 
 	// Start the server on localhost port 8000 and log any errors
 	log.Println("http server started on :8000")
-	err := http.ListenAndServe(":8000", nil)
+	err = http.ListenAndServe(":8000", nil)
 	if err != nil {
 		log.Fatal("ListenAndServe: ", err)
 	}
@@ -50,85 +131,257 @@ func (s *server) handleConnections(w http.ResponseWriter, r *http.Request) {
 
 	// Register our new client
 	client := comm.NewClient(ws)
+	limiter := newRateLimiter(s.rate, s.burst)
+	var current *room
+	defer func() {
+		s.leaveRoom(current, client)
+	}()
 
-	for client.Connected {
+	for client.IsConnected() {
 		action, err := client.HandleInMessages()
 		if err != nil {
 			log.Println(err)
 			continue
 		}
-		s.processAction(client, action)
+		if !limiter.allow(time.Now()) {
+			rejectAction(client, action, "rate limit exceeded")
+			continue
+		}
+		current = s.processAction(client, current, action)
 	}
 }
 
-func (s *server) processAction(client *comm.Client, action game.Action) {
-	// register new player
-	if action.GetType() == game.PlayerJoinActionType {
-		client.PlayerId = action.GetPayload().(game.Player).Id
-		s.clients[client.PlayerId] = client
+// processAction handles room actions and passes game actions to current room, returns room of the client
+func (s *server) processAction(client *comm.Client, current *room, action game.Action) *room {
+	// identity of the connection is known after handshake only
+	switch action.(type) {
+	case game.AuthAction, game.RoomListAction:
+	default:
+		if client.PlayerId == game.ZeroPlayerId {
+			s.send(client, game.AuthFailedAction{
+				Type:    game.AuthFailedActionType,
+				Payload: game.AuthFailedPayload{Reason: "not authenticated"},
+			})
+			return current
+		}
 	}
 
-	// broadcast action to others
-	s.broadcastOthers(client, action)
-
-	// synchronous dispatch func
-	dispatch := func(a game.Action) {
-		if err := s.route(client, a); err != nil {
-			log.Println(err)
+	switch a := action.(type) {
+	case game.AuthAction:
+		s.authenticate(client, a)
+	case game.RoomListAction:
+		s.sendRoomList(client)
+	case game.RoomCreateAction:
+		s.leaveRoom(current, client)
+		current = s.createRoom(game.RoomIdType(uuid.NewString()), a.Payload.Name)
+		s.sendRoomJoined(client, current)
+	case game.RoomJoinAction:
+		next := s.getRoom(a.Payload.RoomId)
+		if next == nil {
+			s.send(client, game.RoomJoinFailedAction{
+				Type: game.RoomJoinFailedActionType,
+				Payload: game.RoomJoinFailedPayload{
+					RoomId: a.Payload.RoomId,
+					Reason: "room not found",
+				},
+			})
+			break
+		}
+		if next != current {
+			s.leaveRoom(current, client)
+			current = next
+		}
+		s.sendRoomJoined(client, current)
+	case game.SaveMatchAction:
+		s.saveMatch(client, current)
+	case game.RoomLeaveAction:
+		s.leaveRoom(current, client)
+		current = nil
+		s.sendRoomList(client)
+	default:
+		if current == nil {
+			current = s.getRoom(defaultRoomId)
 		}
+		current.processAction(client, action)
 	}
-
-	// action handling
-	s.game.HandleAction(action, dispatch)
+	return current
 }
 
-func (s *server) broadcastOthers(client *comm.Client, action game.Action) {
-	switch action.(type) {
-	case game.PlayerJoinAction, game.MapLoadAction:
+// authenticate sets identity of connection from token, new player without token gets new identity and token
+func (s *server) authenticate(client *comm.Client, action game.AuthAction) {
+	fail := func(reason string) {
+		log.Printf("authentication failed: %s", reason)
+		s.send(client, game.AuthFailedAction{
+			Type:    game.AuthFailedActionType,
+			Payload: game.AuthFailedPayload{Reason: reason},
+		})
+	}
+	if client.PlayerId != game.ZeroPlayerId {
+		fail("already authenticated")
 		return
 	}
-	for _, c := range s.clients {
-		if c != client {
-			err := c.Send(action)
-			if err != nil {
-				log.Println(err)
-			}
+	var identity session
+	if action.Payload.Token != "" {
+		var err error
+		if identity, err = s.auth.verify(action.Payload.Token); err != nil {
+			fail(err.Error())
+			return
 		}
+	} else {
+		name := strings.TrimSpace(action.Payload.Name)
+		if name == "" {
+			fail("name missing")
+			return
+		}
+		identity = session{PlayerId: game.PlayerIdType(uuid.New()), Name: name}
+	}
+	token, err := s.auth.issue(identity)
+	if err != nil {
+		log.Println(err)
+		fail("token not issued")
+		return
 	}
+	client.PlayerId = identity.PlayerId
+	client.Name = identity.Name
+	log.Printf("player %s %q authenticated", uuid.UUID(identity.PlayerId), identity.Name)
+	s.send(client, game.AuthSuccessAction{
+		Type: game.AuthSuccessActionType,
+		Payload: game.AuthSuccessPayload{
+			PlayerId: identity.PlayerId,
+			Name:     identity.Name,
+			Token:    token,
+		},
+	})
 }
 
-func (s *server) broadcastAll(action game.Action) {
-	for _, c := range s.clients {
-		err := c.Send(action)
-		if err != nil {
-			log.Println(err)
-		}
+// saveMatch writes snapshot of the room to save directory, only admins may save
+func (s *server) saveMatch(client *comm.Client, r *room) {
+	fail := func(reason string) {
+		s.send(client, game.MatchSaveFailedAction{
+			Type:    game.MatchSaveFailedActionType,
+			Payload: game.MatchSaveFailedPayload{Reason: reason},
+		})
 	}
+	if r == nil {
+		fail("not in room")
+		return
+	}
+	if !s.admins[client.PlayerId] {
+		fail("not admin")
+		return
+	}
+	save := r.save()
+	path := filepath.Join(s.saveDir, fmt.Sprintf("%s-%d.json.gz", r.id, save.Snapshot.Tick))
+	if err := writeMatchSave(path, save); err != nil {
+		log.Println(err)
+		fail("write failed")
+		return
+	}
+	log.Printf("room %s saved to %s", r.id, path)
+	s.send(client, game.MatchSavedAction{
+		Type: game.MatchSavedActionType,
+		Payload: game.MatchSavedPayload{
+			File: path,
+			Tick: save.Snapshot.Tick,
+		},
+	})
 }
 
-// route - handler of outgoing actions
-func (s *server) route(c *comm.Client, action game.Action) error {
-	dispatch := func(a game.Action) {
-		if err := s.route(c, a); err != nil {
-			log.Println(err)
-		}
+// loadMatch resumes saved match in default room, players rejoin with their names
+func (s *server) loadMatch(path string) error {
+	save, err := readMatchSave(path)
+	if err != nil {
+		return err
 	}
-	switch a := action.(type) {
-	case game.MoveStepAction, game.MoveStopAction, game.SpawnUnitAction:
-		s.broadcastAll(a)
-		s.game.HandleAction(a, dispatch)
-	case game.PlayerJoinSuccessAction:
-		if err := c.Send(action); err != nil {
-			return fmt.Errorf("route %w", err)
-		}
-	case game.MapLoadSuccessAction:
-		if err := c.Send(action); err != nil {
-			return fmt.Errorf("route %w", err)
-		}
-		s.game.HandleAction(a, dispatch)
-	default:
-		s.broadcastAll(a)
+	s.getRoom(defaultRoomId).restore(*save)
+	log.Printf("match %s loaded at tick %d", path, save.Snapshot.Tick)
+	return nil
+}
+
+func (s *server) createRoom(id game.RoomIdType, name string) *room {
+	s.mux.Lock()
+	defer s.mux.Unlock()
+	r := newRoom(id, name, newServerGame(game.NewStoreImpl(), s.worldService, s.mode, s.defs, s.mapCfg), s.replayDir)
+	s.rooms[id] = r
+	go r.run()
+	log.Printf("room %s %q created", id, name)
+	return r
+}
+
+func (s *server) getRoom(id game.RoomIdType) *room {
+	s.mux.Lock()
+	defer s.mux.Unlock()
+	return s.rooms[id]
+}
+
+// leaveRoom removes client from room, room without clients is closed
+func (s *server) leaveRoom(r *room, client *comm.Client) {
+	if r == nil {
+		return
 	}
+	s.mux.Lock()
+	defer s.mux.Unlock()
+	if r.leave(client) > 0 || r.id == defaultRoomId {
+		return
+	}
+	if _, ok := s.rooms[r.id]; !ok {
+		return
+	}
+	delete(s.rooms, r.id)
+	r.close()
+	log.Printf("room %s %q closed", r.id, r.name)
+}
 
-	return nil
+func (s *server) sendRoomList(client *comm.Client) {
+	s.mux.Lock()
+	rooms := make([]*room, 0, len(s.rooms))
+	for _, r := range s.rooms {
+		rooms = append(rooms, r)
+	}
+	s.mux.Unlock()
+
+	infos := make([]game.RoomInfo, 0, len(rooms))
+	for _, r := range rooms {
+		infos = append(infos, r.info())
+	}
+	sort.Slice(infos, func(i, j int) bool {
+		return infos[i].Id < infos[j].Id
+	})
+	s.send(client, game.RoomListSuccessAction{
+		Type:    game.RoomListSuccessActionType,
+		Payload: game.RoomListSuccessPayload{Rooms: infos},
+	})
+}
+
+func (s *server) sendRoomJoined(client *comm.Client, r *room) {
+	s.send(client, game.RoomJoinedAction{
+		Type:    game.RoomJoinedActionType,
+		Payload: r.info(),
+	})
+}
+
+func (s *server) send(client *comm.Client, action game.Action) {
+	if err := client.Send(action); err != nil {
+		log.Println(err)
+	}
+}
+
+// rejectAction tells the sender that action was refused, nothing is broadcast
+func rejectAction(client *comm.Client, action game.Action, reason string) {
+	log.Printf("player %s action %s rejected: %s", uuid.UUID(client.PlayerId), action.GetType(), reason)
+	rejected := game.ActionRejectedAction{
+		Type: game.ActionRejectedActionType,
+		Payload: game.ActionRejectedPayload{
+			ActionType: action.GetType(),
+			Reason:     reason,
+		},
+	}
+	// client stops predicting the command
+	if a, ok := action.(game.MoveStartAction); ok {
+		rejected.Payload.Seq = a.Payload.Seq
+	}
+	err := client.Send(rejected)
+	if err != nil {
+		log.Println(err)
+	}
 }
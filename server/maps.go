@@ -0,0 +1,114 @@
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"image"
+	"log"
+	"os"
+	"time"
+
+	"github.com/bmcszk/gptrts/pkg/game"
+	"github.com/bmcszk/gptrts/pkg/world"
+)
+
+const (
+	startCheckMinDelay = time.Second
+	startCheckMaxDelay = time.Minute
+)
+
+var errWorldUnavailable = errors.New("world service unavailable")
+
+// mapConfig describes where players start on the map
+type mapConfig struct {
+	Name       string        `json:"name"`
+	MaxPlayers int           `json:"maxPlayers"` // defaults to number of start positions
+	Starts     []image.Point `json:"starts"`
+}
+
+func loadMapConfig(path string) (*mapConfig, error) {
+	bytes, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	var m mapConfig
+	if err := json.Unmarshal(bytes, &m); err != nil {
+		return nil, fmt.Errorf("map %s: %w", path, err)
+	}
+	if err := m.init(); err != nil {
+		return nil, fmt.Errorf("map %s: %w", path, err)
+	}
+	return &m, nil
+}
+
+func (m *mapConfig) init() error {
+	if len(m.Starts) == 0 {
+		return errors.New("no start positions")
+	}
+	if m.MaxPlayers == 0 {
+		m.MaxPlayers = len(m.Starts)
+	}
+	if m.MaxPlayers < 1 || m.MaxPlayers > len(m.Starts) {
+		return fmt.Errorf("max players %d out of 1..%d start positions", m.MaxPlayers, len(m.Starts))
+	}
+	seen := make(map[image.Point]bool, len(m.Starts))
+	for _, p := range m.Starts {
+		if seen[p] {
+			return fmt.Errorf("start position %s defined twice", p)
+		}
+		seen[p] = true
+	}
+	return nil
+}
+
+// slots returns start positions used by lobby
+func (m *mapConfig) slots() []image.Point {
+	return m.Starts[:m.MaxPlayers]
+}
+
+// checkStarts validates start positions in background, world service may come up after the server so it is
+// retried until it answers. Start on impassable land is error of map file and stops the server.
+func (m *mapConfig) checkStarts(worldService world.WorldService) {
+	delay := startCheckMinDelay
+	for {
+		err := m.validateStarts(worldService)
+		if err == nil {
+			return
+		}
+		if !errors.Is(err, errWorldUnavailable) {
+			log.Fatal(err)
+		}
+		log.Printf("%s, retrying in %s", err, delay)
+		time.Sleep(delay)
+		delay *= 2
+		if delay > startCheckMaxDelay {
+			delay = startCheckMaxDelay
+		}
+	}
+}
+
+// validateStarts checks with world service that players start on passable land
+func (m *mapConfig) validateStarts(worldService world.WorldService) error {
+	for _, p := range m.slots() {
+		resp, err := worldService.Load(world.WorldRequest{MinX: p.X, MinY: p.Y, MaxX: p.X, MaxY: p.Y})
+		if err != nil {
+			return fmt.Errorf("start position %s: %w: %w", p, errWorldUnavailable, err)
+		}
+		found := false
+		for _, t := range resp.Tiles {
+			if t.Point != p {
+				continue
+			}
+			found = true
+			tile := t
+			if !game.TerrainOf(&game.Tile{Tile: &tile}).Passable {
+				return fmt.Errorf("start position %s not passable: %s", p, t.LandType)
+			}
+		}
+		if !found {
+			return fmt.Errorf("start position %s not found on map", p)
+		}
+	}
+	return nil
+}
@@ -0,0 +1,243 @@
+package main
+
+import (
+	"image"
+	"log"
+
+	"github.com/bmcszk/gptrts/pkg/game"
+	"github.com/google/uuid"
+)
+
+const (
+	chaseInterval        = game.TickRate / 2 // ticks between path updates of units chasing their targets
+	resourceSearchRadius = 6                 // how far workers look for another node when theirs is depleted
+)
+
+// handleMoveStartAction plans path on server and publishes it, so every game logic moves the unit the same way
+func (g *serverGame) handleMoveStartAction(action game.MoveStartAction, dispatch game.DispatchFunc) {
+	unit := g.store.GetUnitById(action.Payload.UnitId)
+	if unit == nil {
+		return
+	}
+	g.cancelOrders(unit, dispatch)
+	pathAction := newPathAction(unit, unit.Path, unit.Costs, unit.Step)
+	path, costs, err := game.PlanPath(g.store, unit, action.Payload.Point)
+	if err != nil {
+		// unit keeps its path, client still gets acknowledgement of the command
+		log.Printf("unit %s cannot move to %v: %s", uuid.UUID(unit.Id), action.Payload.Point, err)
+	} else {
+		pathAction = newPathAction(unit, path, costs, 0)
+	}
+	pathAction.Payload.Seq = action.Payload.Seq
+	dispatch(pathAction)
+}
+
+func (g *serverGame) handleAttackAction(action game.AttackAction, dispatch game.DispatchFunc) {
+	unit := g.store.GetUnitById(action.Payload.UnitId)
+	if unit == nil {
+		return
+	}
+	g.cancelOrders(unit, dispatch)
+	dispatch(newTargetSetAction(unit.Id, action.Payload.TargetId, action.Payload.BuildingId))
+}
+
+func (g *serverGame) handleGatherStartAction(action game.GatherStartAction, dispatch game.DispatchFunc) {
+	unit := g.store.GetUnitById(action.Payload.UnitId)
+	if unit == nil {
+		return
+	}
+	g.cancelOrders(unit, dispatch)
+	dispatch(g.newGatherSetAction(unit.Id, &action.Payload.Point, g.dropoff(unit)))
+}
+
+// cancelOrders stops attacking and gathering before unit gets new order
+func (g *serverGame) cancelOrders(unit *game.Unit, dispatch game.DispatchFunc) {
+	if unit.Target != game.ZeroUnitId || unit.TargetBuilding != game.ZeroBuildingId {
+		dispatch(newTargetSetAction(unit.Id, game.ZeroUnitId, game.ZeroBuildingId))
+	}
+	if unit.Gather != nil {
+		dispatch(g.newGatherSetAction(unit.Id, nil, unit.Dropoff))
+	}
+}
+
+// dropoff returns point where unit delivers gathered resources, closest drop-off building or starting point
+func (g *serverGame) dropoff(unit *game.Unit) image.Point {
+	p := unit.Position.ImagePoint()
+	if unit.Gather != nil {
+		p = *unit.Gather
+	}
+	var found *image.Point
+	bestDist := 0.0
+	for _, b := range g.store.GetBuildingsByPlayerId(unit.Owner) {
+		if !b.IsComplete() || !b.HasAbility(game.DropoffAbility) {
+			continue
+		}
+		closest := b.ClosestPoint(p)
+		if d := game.Dist(p, closest); found == nil || d < bestDist {
+			bestDist = d
+			found = &closest
+		}
+	}
+	if found != nil {
+		return *found
+	}
+	if player, ok := g.store.GetPlayer(unit.Owner); ok {
+		return player.Start.ImagePoint()
+	}
+	return p
+}
+
+// UpdateOrders issues movement needed to carry out unit orders, e.g. chasing attack target
+func (g *serverGame) UpdateOrders(dispatch game.DispatchFunc) {
+	for _, u := range g.store.GetAllUnits() {
+		if u.Target != game.ZeroUnitId {
+			if target := g.store.GetUnitById(u.Target); target != nil {
+				g.chase(u, target, dispatch)
+			}
+		} else if u.TargetBuilding != game.ZeroBuildingId {
+			if target := g.store.GetBuildingById(u.TargetBuilding); target != nil {
+				g.siege(u, target, dispatch)
+			}
+		}
+		if u.Gather != nil {
+			g.gather(u, dispatch)
+		}
+	}
+}
+
+func (g *serverGame) chase(u, target *game.Unit, dispatch game.DispatchFunc) {
+	targetP := target.Position.ImagePoint()
+	if u.InRange(target) {
+		// stop on the next tile
+		if u.IsMoving() && len(u.Path) > u.Step+1 {
+			dispatch(newPathAction(u, u.Path[:u.Step+1], u.Costs[:u.Step+1], u.Step))
+		}
+		return
+	}
+	if u.IsMoving() && (g.Tick()%chaseInterval != 0 || game.Dist(u.Path[len(u.Path)-1], targetP) <= 1.5) {
+		return
+	}
+	if err := g.moveNear(u, targetP, dispatch); err != nil {
+		log.Printf("unit %s cannot reach target: %s", uuid.UUID(u.Id), err)
+		dispatch(newTargetSetAction(u.Id, game.ZeroUnitId, game.ZeroBuildingId))
+	}
+}
+
+// siege approaches building to attack, buildings do not move so it is enough to plan once
+func (g *serverGame) siege(u *game.Unit, target *game.Building, dispatch game.DispatchFunc) {
+	if u.IsMoving() || u.InRangeOfBuilding(target) {
+		return
+	}
+	if err := g.moveNear(u, target.ClosestPoint(u.Position.ImagePoint()), dispatch); err != nil {
+		log.Printf("unit %s cannot reach building: %s", uuid.UUID(u.Id), err)
+		dispatch(newTargetSetAction(u.Id, game.ZeroUnitId, game.ZeroBuildingId))
+	}
+}
+
+// gather walks worker between resource node and drop-off point, gathering itself is simulated by game logic
+func (g *serverGame) gather(u *game.Unit, dispatch game.DispatchFunc) {
+	if u.IsMoving() {
+		return
+	}
+	t, ok := g.store.GetTile(*u.Gather)
+	depleted := !ok || t.Resource == nil || t.Resource.Amount == 0
+
+	target := *u.Gather
+	if u.MustDeliver(t) {
+		if dropoff := g.dropoff(u); dropoff != u.Dropoff {
+			// closer drop-off building was completed
+			dispatch(g.newGatherSetAction(u.Id, u.Gather, dropoff))
+			return
+		}
+		target = u.Dropoff
+	} else if depleted {
+		var next *image.Point
+		if ok && t.Resource != nil {
+			next = g.findResourceNode(*u.Gather, t.Resource.Type)
+		}
+		dispatch(g.newGatherSetAction(u.Id, next, u.Dropoff))
+		return
+	}
+	if err := g.moveNear(u, target, dispatch); err != nil {
+		log.Printf("unit %s cannot reach %v: %s", uuid.UUID(u.Id), target, err)
+		dispatch(g.newGatherSetAction(u.Id, nil, u.Dropoff))
+	}
+}
+
+// findResourceNode returns closest not depleted node of given type
+func (g *serverGame) findResourceNode(near image.Point, resourceType game.ResourceType) *image.Point {
+	var found *image.Point
+	bestDist := float64(resourceSearchRadius + 1)
+	for x := near.X - resourceSearchRadius; x <= near.X+resourceSearchRadius; x++ {
+		for y := near.Y - resourceSearchRadius; y <= near.Y+resourceSearchRadius; y++ {
+			p := image.Pt(x, y)
+			t, ok := g.store.GetTile(p)
+			if !ok || t.Resource == nil || t.Resource.Type != resourceType || t.Resource.Amount == 0 {
+				continue
+			}
+			if d := game.Dist(near, p); d < bestDist {
+				bestDist = d
+				found = &p
+			}
+		}
+	}
+	return found
+}
+
+// moveNear sends unit next to the point unless it is there already
+func (g *serverGame) moveNear(u *game.Unit, p image.Point, dispatch game.DispatchFunc) error {
+	path, costs, err := game.PlanPathNear(g.store, u, p, 1)
+	if err != nil {
+		return err
+	}
+	if len(path) < 2 {
+		// already as close as possible
+		return nil
+	}
+	dispatch(newPathAction(u, path, costs, 0))
+	return nil
+}
+
+func newPathAction(unit *game.Unit, path []image.Point, costs []int, step int) game.MoveStepAction {
+	return game.MoveStepAction{
+		Type: game.MoveStepActionType,
+		Payload: game.MoveStepPayload{
+			UnitId:   unit.Id,
+			Position: unit.Position,
+			Path:     path,
+			Costs:    costs,
+			Step:     step,
+			Seq:      unit.Seq,
+		},
+	}
+}
+
+func newTargetSetAction(unitId, targetId game.UnitIdType, buildingId game.BuildingIdType) game.TargetSetAction {
+	return game.TargetSetAction{
+		Type: game.TargetSetActionType,
+		Payload: game.TargetPayload{
+			UnitId:     unitId,
+			TargetId:   targetId,
+			BuildingId: buildingId,
+		},
+	}
+}
+
+func (g *serverGame) newGatherSetAction(unitId game.UnitIdType, p *image.Point, dropoff image.Point) game.GatherSetAction {
+	action := game.GatherSetAction{
+		Type: game.GatherSetActionType,
+		Payload: game.GatherSetPayload{
+			UnitId:  unitId,
+			Point:   p,
+			Dropoff: dropoff,
+		},
+	}
+	if p == nil {
+		return action
+	}
+	if t, ok := g.store.GetTile(*p); ok && t.Resource != nil {
+		node := *t.Resource
+		action.Payload.Node = &node
+	}
+	return action
+}
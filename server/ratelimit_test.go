@@ -0,0 +1,57 @@
+package main
+
+import (
+	"testing"
+	"time"
+)
+
+func TestRateLimiterAllow(t *testing.T) {
+	start := time.Now()
+	tests := []struct {
+		name  string
+		rate  float64
+		burst int
+		calls []time.Duration // offsets from start of calls
+		want  []bool
+	}{
+		{
+			name:  "burst then empty",
+			rate:  1,
+			burst: 3,
+			calls: []time.Duration{0, 0, 0, 0},
+			want:  []bool{true, true, true, false},
+		},
+		{
+			name:  "refill by rate",
+			rate:  10,
+			burst: 1,
+			calls: []time.Duration{0, 0, 50 * time.Millisecond, 100 * time.Millisecond, 100 * time.Millisecond},
+			want:  []bool{true, false, false, true, false},
+		},
+		{
+			name:  "refill capped by burst",
+			rate:  10,
+			burst: 2,
+			calls: []time.Duration{0, 0, 0, 10 * time.Second, 10 * time.Second, 10 * time.Second},
+			want:  []bool{true, true, false, true, true, false},
+		},
+		{
+			name:  "zero rate disables limit",
+			rate:  0,
+			burst: 1,
+			calls: []time.Duration{0, 0, 0},
+			want:  []bool{true, true, true},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			l := newRateLimiter(tt.rate, tt.burst)
+			l.last = start
+			for i, d := range tt.calls {
+				if got := l.allow(start.Add(d)); got != tt.want[i] {
+					t.Errorf("call %d at %v: allow() = %v, want %v", i, d, got, tt.want[i])
+				}
+			}
+		})
+	}
+}
@@ -0,0 +1,80 @@
+package main
+
+import (
+	"fmt"
+	"log"
+	"os"
+	"path/filepath"
+	"time"
+
+	"github.com/bmcszk/gptrts/pkg/game"
+)
+
+// record - appends action to replay of the match, recording starts with the first action of the started match
+// and stops when the match is over. Clients get fog filtered actions, replay gets all of them.
+func (r *room) record(action game.Action) {
+	if r.replayDir == "" || r.recorded {
+		return
+	}
+	if r.recorder == nil {
+		if !r.game.lobby.IsPlaying() {
+			return
+		}
+		if err := r.startRecording(); err != nil {
+			log.Printf("room %s replay not recorded: %s", r.id, err)
+			r.recorded = true
+			return
+		}
+	}
+	if err := r.recorder.Record(action); err != nil {
+		log.Println(err)
+	}
+}
+
+func (r *room) startRecording() error {
+	if err := os.MkdirAll(r.replayDir, 0o755); err != nil {
+		return err
+	}
+	path := filepath.Join(r.replayDir, fmt.Sprintf("%s-%d.replay.gz", r.id, time.Now().Unix()))
+	f, err := os.Create(path)
+	if err != nil {
+		return err
+	}
+	lobby := r.game.lobby
+	lobby.Slots = append([]game.Slot{}, r.game.lobby.Slots...)
+	recorder, err := game.NewReplayWriter(f, game.ReplayHeader{
+		Mode:        r.game.mode,
+		Definitions: r.game.defs,
+		Snapshot:    r.game.Snapshot(),
+		Lobby:       lobby,
+	})
+	if err != nil {
+		f.Close()
+		return err
+	}
+	r.recorder = recorder
+	log.Printf("room %s recording replay to %s", r.id, path)
+	return nil
+}
+
+// flushRecording - recorded actions of the tick are written to the file
+func (r *room) flushRecording() {
+	if r.recorder == nil {
+		return
+	}
+	if err := r.recorder.Flush(); err != nil {
+		log.Println(err)
+	}
+}
+
+func (r *room) stopRecording() {
+	if r.recorder == nil {
+		return
+	}
+	if err := r.recorder.Close(); err != nil {
+		log.Println(err)
+	}
+	r.recorder = nil
+	r.recorded = true
+	log.Printf("room %s replay recorded", r.id)
+}
@@ -0,0 +1,72 @@
+package main
+
+import (
+	"image"
+
+	"github.com/bmcszk/gptrts/pkg/game"
+)
+
+// reservations - in lockstep mode actions of accepted commands wait in tick bundle until the next tick,
+// validation and handlers of later commands in the same tick count with them
+type reservations struct {
+	stockpiles map[game.PlayerIdType]game.Resources // stockpile after pending spending and refunds
+	footprints []image.Rectangle                    // areas of pending buildings
+	queued     map[game.BuildingIdType]int          // units added to queues
+	canceled   map[game.UnitIdType]bool             // units removed from queues
+}
+
+func newReservations() reservations {
+	return reservations{
+		stockpiles: make(map[game.PlayerIdType]game.Resources),
+		queued:     make(map[game.BuildingIdType]int),
+		canceled:   make(map[game.UnitIdType]bool),
+	}
+}
+
+// resources returns stockpile of player including commands not applied yet
+func (g *serverGame) resources(playerId game.PlayerIdType) game.Resources {
+	if resources, ok := g.reserved.stockpiles[playerId]; ok {
+		return resources
+	}
+	return g.store.GetResources(playerId)
+}
+
+// setResources publishes new stockpile of player, it is reserved until the tick bundle is applied
+func (g *serverGame) setResources(playerId game.PlayerIdType, resources game.Resources, dispatch game.DispatchFunc) {
+	if g.mode == game.LockstepMode {
+		g.reserved.stockpiles[playerId] = resources
+	}
+	dispatch(newPlayerResourcesUpdatedAction(playerId, resources))
+}
+
+func (g *serverGame) reserveFootprint(position, footprint image.Point) {
+	if g.mode == game.LockstepMode {
+		g.reserved.footprints = append(g.reserved.footprints, image.Rectangle{Min: position, Max: position.Add(footprint)})
+	}
+}
+
+func (g *serverGame) reserveQueued(buildingId game.BuildingIdType) {
+	if g.mode == game.LockstepMode {
+		g.reserved.queued[buildingId]++
+	}
+}
+
+func (g *serverGame) reserveCanceled(unitId game.UnitIdType) {
+	if g.mode == game.LockstepMode {
+		g.reserved.canceled[unitId] = true
+	}
+}
+
+func (g *serverGame) footprintReserved(p image.Point) bool {
+	for _, r := range g.reserved.footprints {
+		if p.In(r) {
+			return true
+		}
+	}
+	return false
+}
+
+// releaseReservations - tick bundle was applied, state in store is up to date
+func (g *serverGame) releaseReservations() {
+	g.reserved = newReservations()
+}
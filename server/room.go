@@ -0,0 +1,335 @@
+package main
+
+import (
+	"fmt"
+	"log"
+	"sync"
+	"time"
+
+	"github.com/bmcszk/gptrts/pkg/comm"
+	"github.com/bmcszk/gptrts/pkg/game"
+)
+
+// room is a match with its own world, simulation and connected players
+type room struct {
+	id      game.RoomIdType
+	name    string
+	game    *serverGame
+	clients map[game.PlayerIdType]*comm.Client
+	// spectators get all broadcasts and send no commands
+	spectators map[game.PlayerIdType]*comm.Client
+	mux        *sync.Mutex  // guards game state between connections and tick loop
+	pending    game.Actions // commands collected for the next tick bundle, lockstep mode only
+	done       chan struct{}
+	// replay of the match, see record
+	replayDir string
+	recorder  *game.ReplayWriter
+	recorded  bool
+}
+
+func newRoom(id game.RoomIdType, name string, g *serverGame, replayDir string) *room {
+	return &room{
+		id:         id,
+		name:       name,
+		game:       g,
+		clients:    make(map[game.PlayerIdType]*comm.Client, 0), // connected clients,
+		spectators: make(map[game.PlayerIdType]*comm.Client, 0),
+		mux:        &sync.Mutex{},
+		done:       make(chan struct{}),
+		replayDir:  replayDir,
+	}
+}
+
+func (r *room) info() game.RoomInfo {
+	r.mux.Lock()
+	defer r.mux.Unlock()
+	return game.RoomInfo{
+		Id:         r.id,
+		Name:       r.name,
+		Players:    len(r.game.store.GetAllPlayers()),
+		MaxPlayers: len(r.game.lobby.Slots),
+	}
+}
+
+// save - snapshot of the match between ticks
+func (r *room) save() matchSave {
+	r.mux.Lock()
+	defer r.mux.Unlock()
+	return r.game.save()
+}
+
+// restore - resumes saved match, room must be empty
+func (r *room) restore(s matchSave) {
+	r.mux.Lock()
+	defer r.mux.Unlock()
+	r.game.restore(s)
+}
+
+// run - simulation loop, ends when room is closed
+func (r *room) run() {
+	ticker := time.NewTicker(time.Second / game.TickRate)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ticker.C:
+			r.tick()
+		case <-r.done:
+			r.mux.Lock()
+			r.stopRecording()
+			r.mux.Unlock()
+			return
+		}
+	}
+}
+
+func (r *room) close() {
+	close(r.done)
+}
+
+func (r *room) tick() {
+	r.mux.Lock()
+	defer r.mux.Unlock()
+	dispatch := func(a game.Action) {
+		if err := r.route(nil, a); err != nil {
+			log.Println(err)
+		}
+	}
+	r.game.applyLoadedMaps()
+	if !r.game.lobby.IsPlaying() {
+		r.game.UpdateLobby(dispatch)
+		return
+	}
+	// match is over, nothing is simulated and no more bundles are sent
+	if r.game.Result() != nil {
+		r.stopRecording()
+		return
+	}
+	if r.game.mode == game.LockstepMode {
+		r.lockstepTick()
+	} else {
+		r.game.Update(dispatch)
+	}
+	defer r.flushRecording()
+	r.game.UpdateProduction(dispatch)
+	r.game.UpdateOrders(dispatch)
+	r.game.UpdateVictory(dispatch)
+	if r.game.mode == game.AuthoritativeMode {
+		r.updateVision()
+	}
+}
+
+// updateVision - clients get units which entered or left vision of their player
+func (r *room) updateVision() {
+	for playerId, c := range r.clients {
+		for _, a := range r.game.UpdateVision(playerId) {
+			if err := c.Send(a); err != nil {
+				log.Println(err)
+			}
+		}
+	}
+}
+
+// lockstepTick - sends commands collected since last tick as numbered bundle, every game logic applies it the same way
+func (r *room) lockstepTick() {
+	action := game.TickAction{
+		Type: game.TickActionType,
+		Payload: game.TickPayload{
+			Tick:    r.game.Tick(),
+			Actions: r.pending,
+		},
+	}
+	r.pending = nil
+	r.record(action)
+
+	// consequences of simulation are computed by every game logic, no need to send them
+	var dispatch game.DispatchFunc
+	dispatch = func(a game.Action) {
+		r.game.HandleAction(a, dispatch)
+	}
+	r.game.HandleAction(action, dispatch)
+	r.game.releaseReservations()
+	r.broadcastAll(action)
+}
+
+func (r *room) processAction(client *comm.Client, action game.Action) {
+	r.mux.Lock()
+	defer r.mux.Unlock()
+
+	// register new player or spectator, identity comes from authentication
+	switch a := action.(type) {
+	case game.PlayerJoinAction:
+		if a.Payload.Id != client.PlayerId {
+			rejectAction(client, action, "player mismatch")
+			return
+		}
+		if r.isSpectator(client) {
+			rejectAction(client, action, "spectator cannot play")
+			return
+		}
+		// name is part of identity
+		a.Payload.Name = client.Name
+		action = a
+		r.clients[client.PlayerId] = client
+	case game.SpectatorJoinAction:
+		if a.Payload.Id != client.PlayerId {
+			rejectAction(client, action, "player mismatch")
+			return
+		}
+		// spectators see whole match, participants would bypass their fog of war
+		if _, ok := r.clients[client.PlayerId]; ok || r.game.isParticipant(client.PlayerId) {
+			rejectAction(client, action, "player cannot spectate")
+			return
+		}
+		a.Payload.Name = client.Name
+		action = a
+		r.spectators[client.PlayerId] = client
+	default:
+		if r.isSpectator(client) && action.GetType() != game.MapLoadActionType {
+			rejectAction(client, action, "spectator cannot act")
+			return
+		}
+	}
+
+	// clients send only intents, state changes are produced by server
+	if err := r.game.ValidateAction(client.PlayerId, action); err != nil {
+		rejectAction(client, action, err.Error())
+		return
+	}
+
+	// synchronous dispatch func
+	dispatch := func(a game.Action) {
+		if err := r.route(client, a); err != nil {
+			log.Println(err)
+		}
+	}
+
+	// action handling
+	r.game.HandleAction(action, dispatch)
+}
+
+func (r *room) isSpectator(client *comm.Client) bool {
+	c, ok := r.spectators[client.PlayerId]
+	return ok && c == client
+}
+
+// leave - client stops receiving room updates, returns number of clients and spectators left
+func (r *room) leave(client *comm.Client) int {
+	r.mux.Lock()
+	defer r.mux.Unlock()
+	if r.isSpectator(client) {
+		delete(r.spectators, client.PlayerId)
+	}
+	if c, ok := r.clients[client.PlayerId]; ok && c == client {
+		delete(r.clients, client.PlayerId)
+		r.game.ForgetPlayer(client.PlayerId)
+		if r.game.LeaveLobby(client.PlayerId) {
+			if err := r.route(nil, r.game.newLobbyStateAction()); err != nil {
+				log.Println(err)
+			}
+		}
+	}
+	return len(r.clients) + len(r.spectators)
+}
+
+// reject - client which cannot join gets the reason and no more room updates
+func (r *room) reject(c *comm.Client, action game.JoinRejectedAction) error {
+	if registered, ok := r.clients[c.PlayerId]; ok && registered == c {
+		delete(r.clients, c.PlayerId)
+	}
+	if err := c.Send(action); err != nil {
+		return fmt.Errorf("route %w", err)
+	}
+	return nil
+}
+
+func (r *room) broadcastAll(action game.Action) {
+	for _, c := range r.clients {
+		err := c.Send(action)
+		if err != nil {
+			log.Println(err)
+		}
+	}
+	r.broadcastSpectators(action)
+}
+
+func (r *room) broadcastSpectators(action game.Action) {
+	for _, c := range r.spectators {
+		if err := c.Send(action); err != nil {
+			log.Println(err)
+		}
+	}
+}
+
+// broadcastObserved - sends action only to clients whose player may observe it, see serverGame.Observes
+func (r *room) broadcastObserved(action game.Action) {
+	for playerId, c := range r.clients {
+		if !r.game.Observes(playerId, action) {
+			continue
+		}
+		if err := c.Send(action); err != nil {
+			log.Println(err)
+		}
+	}
+	r.broadcastSpectators(action)
+}
+
+// route - handler of outgoing actions
+func (r *room) route(c *comm.Client, action game.Action) error {
+	dispatch := func(a game.Action) {
+		if err := r.route(c, a); err != nil {
+			log.Println(err)
+		}
+	}
+	if r.game.mode == game.LockstepMode {
+		return r.routeLockstep(c, action)
+	}
+	switch a := action.(type) {
+	case game.PlayerJoinSuccessAction, game.ResyncAction:
+		if err := c.Send(action); err != nil {
+			return fmt.Errorf("route %w", err)
+		}
+	case game.JoinRejectedAction:
+		return r.reject(c, a)
+	case game.MapLoadSuccessAction:
+		if err := c.Send(action); err != nil {
+			return fmt.Errorf("route %w", err)
+		}
+		r.record(a)
+		r.game.HandleAction(a, dispatch)
+	default:
+		// state change
+		r.broadcastObserved(a)
+		r.record(a)
+		r.game.HandleAction(a, dispatch)
+	}
+
+	return nil
+}
+
+// routeLockstep - state changes are queued for the next tick bundle, responses go directly to client
+func (r *room) routeLockstep(c *comm.Client, action game.Action) error {
+	switch a := action.(type) {
+	case game.PlayerJoinSuccessAction, game.ResyncAction:
+		if err := c.Send(action); err != nil {
+			return fmt.Errorf("route %w", err)
+		}
+	case game.JoinRejectedAction:
+		return r.reject(c, a)
+	case game.MapLoadSuccessAction:
+		// tiles and resource nodes are part of simulation, every game logic must know the same ones
+		r.broadcastAll(a)
+		r.record(a)
+		r.game.HandleAction(a, func(game.Action) {})
+	case game.DesyncAction:
+		r.broadcastAll(a)
+	case game.LobbyStateAction:
+		// lobby is not part of simulation, match starts with the first tick bundle
+		r.broadcastAll(a)
+		r.record(a)
+		r.game.HandleAction(a, func(game.Action) {})
+	default:
+		r.pending = append(r.pending, a)
+	}
+
+	return nil
+}
@@ -0,0 +1,87 @@
+package main
+
+import (
+	"compress/gzip"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+
+	"github.com/bmcszk/gptrts/pkg/game"
+)
+
+// saveVersion - version of save file format, older files are rejected when format changes
+const saveVersion = 1
+
+// matchSave is save file of a match, it is gzip compressed JSON
+type matchSave struct {
+	Version  int
+	Mode     game.NetworkMode
+	Snapshot game.Snapshot
+	Lobby    game.Lobby
+	KeyBuilt []game.PlayerIdType
+}
+
+func (g *serverGame) save() matchSave {
+	s := matchSave{
+		Version:  saveVersion,
+		Mode:     g.mode,
+		Snapshot: g.Snapshot(),
+		Lobby:    g.lobby,
+		KeyBuilt: make([]game.PlayerIdType, 0),
+	}
+	s.Lobby.Slots = append([]game.Slot{}, g.lobby.Slots...)
+	for id, built := range g.keyBuilt {
+		if built {
+			s.KeyBuilt = append(s.KeyBuilt, id)
+		}
+	}
+	return s
+}
+
+// restore resumes saved match in game which has not started yet
+func (g *serverGame) restore(s matchSave) {
+	g.mode = s.Mode
+	g.lobby = s.Lobby
+	for _, id := range s.KeyBuilt {
+		g.keyBuilt[id] = true
+	}
+	g.Restore(s.Snapshot)
+}
+
+func writeMatchSave(path string, s matchSave) error {
+	if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+		return err
+	}
+	f, err := os.Create(path)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+	zw := gzip.NewWriter(f)
+	if err := json.NewEncoder(zw).Encode(s); err != nil {
+		return err
+	}
+	return zw.Close()
+}
+
+func readMatchSave(path string) (*matchSave, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+	zr, err := gzip.NewReader(f)
+	if err != nil {
+		return nil, fmt.Errorf("save %s: %w", path, err)
+	}
+	defer zr.Close()
+	var s matchSave
+	if err := json.NewDecoder(zr).Decode(&s); err != nil {
+		return nil, fmt.Errorf("save %s: %w", path, err)
+	}
+	if s.Version != saveVersion {
+		return nil, fmt.Errorf("save %s: version %d not supported, want %d", path, s.Version, saveVersion)
+	}
+	return &s, nil
+}
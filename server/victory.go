@@ -0,0 +1,206 @@
+package main
+
+import (
+	"bytes"
+	"log"
+	"sort"
+
+	"github.com/bmcszk/gptrts/pkg/game"
+	"github.com/google/uuid"
+)
+
+const victoryInterval = game.TickRate // ticks between evaluations of win conditions
+
+// UpdateVictory evaluates win conditions, defeated players and the final result are broadcast to everybody
+func (g *serverGame) UpdateVictory(dispatch game.DispatchFunc) {
+	if g.Result() != nil || g.Tick()%victoryInterval != 0 {
+		return
+	}
+	victory := g.defs.Victory
+	players := g.slotPlayers()
+
+	// in lockstep mode defeats are applied with the next tick bundle, so they are counted here
+	remaining := make([]game.PlayerIdType, 0, len(players))
+	var reason game.VictoryCondition
+	for _, player := range players {
+		if player.Defeated {
+			continue
+		}
+		defeat, ok := g.defeatReason(player.Id)
+		if !ok {
+			remaining = append(remaining, player.Id)
+			continue
+		}
+		reason = defeat
+		dispatch(game.PlayerDefeatedAction{
+			Type: game.PlayerDefeatedActionType,
+			Payload: game.PlayerDefeatedPayload{
+				PlayerId: player.Id,
+				Reason:   defeat,
+			},
+		})
+	}
+
+	// remaining allies win together
+	if len(remaining) < len(players) && g.allAllied(remaining) {
+		dispatch(g.newGameOverAction(remaining, reason))
+		return
+	}
+
+	if victory.Has(game.ScoreCondition) && g.Tick() >= int(victory.TimeLimit*game.TickRate) {
+		dispatch(g.newGameOverAction(g.topScorers(remaining), game.ScoreCondition))
+	}
+}
+
+func (g *serverGame) allAllied(playerIds []game.PlayerIdType) bool {
+	for i, p1 := range playerIds {
+		for _, p2 := range playerIds[i+1:] {
+			if !game.Allied(g.store, p1, p2) {
+				return false
+			}
+		}
+	}
+	return true
+}
+
+// defeatReason returns condition under which player is defeated
+func (g *serverGame) defeatReason(playerId game.PlayerIdType) (game.VictoryCondition, bool) {
+	victory := g.defs.Victory
+	units := g.store.GetUnitsByPlayerId(playerId)
+	buildings := g.store.GetBuildingsByPlayerId(playerId)
+	if victory.Has(game.AnnihilationCondition) && len(units) == 0 && len(buildings) == 0 {
+		return game.AnnihilationCondition, true
+	}
+	if victory.Has(game.BuildingCondition) {
+		keyBuildings := 0
+		for _, b := range buildings {
+			if b.IsComplete() && victory.IsKeyBuilding(b.Type) {
+				keyBuildings++
+			}
+		}
+		// players start without buildings, so only those who have built a key building can lose it
+		if keyBuildings > 0 {
+			g.keyBuilt[playerId] = true
+		} else if g.keyBuilt[playerId] {
+			return game.BuildingCondition, true
+		}
+	}
+	return "", false
+}
+
+// score is value of stockpile, units and complete buildings of the player
+func (g *serverGame) score(playerId game.PlayerIdType) int {
+	score := 0
+	for _, amount := range g.store.GetResources(playerId) {
+		score += amount
+	}
+	for _, u := range g.store.GetUnitsByPlayerId(playerId) {
+		t, _ := g.defs.UnitType(u.Type)
+		for _, amount := range t.Cost {
+			score += amount
+		}
+	}
+	for _, b := range g.store.GetBuildingsByPlayerId(playerId) {
+		if !b.IsComplete() {
+			continue
+		}
+		t, _ := g.defs.BuildingType(b.Type)
+		for _, amount := range t.Cost {
+			score += amount
+		}
+	}
+	return score
+}
+
+// topScorers returns players of alliance with the highest total score, nobody when alliances are tied
+func (g *serverGame) topScorers(playerIds []game.PlayerIdType) []game.PlayerIdType {
+	var winners []game.PlayerIdType
+	best, tied := -1, false
+	for _, id := range playerIds {
+		alliance := g.allianceOf(id, playerIds)
+		score := 0
+		for _, member := range alliance {
+			score += g.score(member)
+		}
+		if score > best {
+			winners, best, tied = alliance, score, false
+		} else if score == best && !containsPlayer(winners, id) {
+			tied = true
+		}
+	}
+	if best < 0 || tied {
+		return nil
+	}
+	return winners
+}
+
+// allianceOf returns player and its allies among players
+func (g *serverGame) allianceOf(playerId game.PlayerIdType, playerIds []game.PlayerIdType) []game.PlayerIdType {
+	alliance := make([]game.PlayerIdType, 0)
+	for _, id := range playerIds {
+		if id == playerId || game.Allied(g.store, playerId, id) {
+			alliance = append(alliance, id)
+		}
+	}
+	return alliance
+}
+
+func containsPlayer(playerIds []game.PlayerIdType, playerId game.PlayerIdType) bool {
+	for _, id := range playerIds {
+		if id == playerId {
+			return true
+		}
+	}
+	return false
+}
+
+func (g *serverGame) newGameOverAction(winners []game.PlayerIdType, reason game.VictoryCondition) game.GameOverAction {
+	action := game.GameOverAction{
+		Type: game.GameOverActionType,
+		Payload: game.GameOverPayload{
+			Winners: winners,
+			Reason:  reason,
+			Tick:    g.Tick(),
+			Scores:  make([]game.PlayerScore, 0),
+		},
+	}
+	for _, player := range g.slotPlayers() {
+		action.Payload.Scores = append(action.Payload.Scores, game.PlayerScore{
+			PlayerId: player.Id,
+			Score:    g.score(player.Id),
+		})
+	}
+	return action
+}
+
+// handleGameOverAction logs result of the match
+func (g *serverGame) handleGameOverAction(action game.GameOverAction) {
+	result := action.Payload
+	if len(result.Winners) == 0 {
+		log.Printf("game over at tick %d by %s: draw", result.Tick, result.Reason)
+	}
+	for _, id := range result.Winners {
+		log.Printf("game over at tick %d by %s: player %s won", result.Tick, result.Reason, uuid.UUID(id))
+	}
+	for _, s := range result.Scores {
+		log.Printf("player %s score %d", uuid.UUID(s.PlayerId), s.Score)
+	}
+}
+
+// slotPlayers returns players who took part in the match, players joining later only watch
+func (g *serverGame) slotPlayers() []*game.Player {
+	players := make([]*game.Player, 0)
+	for _, player := range sortedPlayers(g.store.GetAllPlayers()) {
+		if g.lobby.SlotOf(player.Id) >= 0 {
+			players = append(players, player)
+		}
+	}
+	return players
+}
+
+func sortedPlayers(players []*game.Player) []*game.Player {
+	sort.Slice(players, func(i, j int) bool {
+		return bytes.Compare(players[i].Id[:], players[j].Id[:]) < 0
+	})
+	return players
+}